@@ -3,12 +3,14 @@ package kratos
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/health"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/transport"
@@ -33,6 +35,9 @@ type App struct {
 	cancel   func()
 	lk       sync.Mutex
 	instance *registry.ServiceInstance
+	stopOnce sync.Once
+	// stopKeepAlive stops the keep-alive loops of the registration.
+	stopKeepAlive func()
 }
 
 // New create an application lifecycle manager.
@@ -42,6 +47,7 @@ func New(opts ...Option) *App {
 		logger:           log.NewHelper(log.DefaultLogger),
 		sigs:             []os.Signal{syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT},
 		registrarTimeout: 10 * time.Second,
+		readyTimeout:     10 * time.Second,
 		stopTimeout:      10 * time.Second,
 	}
 	if id, err := uuid.NewUUID(); err == nil {
@@ -50,6 +56,17 @@ func New(opts ...Option) *App {
 	for _, opt := range opts {
 		opt(&o)
 	}
+	// a server passed more than once, to Server or Stage, is started once.
+	servers := o.servers
+	o.servers = nil
+	for _, s := range o.staged {
+		servers = append(servers, s.srv)
+	}
+	for _, srv := range servers {
+		if !containsServer(o.servers, srv) {
+			o.servers = append(o.servers, srv)
+		}
+	}
 	ctx, cancel := context.WithCancel(o.ctx)
 	return &App{
 		ctx:    ctx,
@@ -84,33 +101,78 @@ func (a *App) Run() error {
 	if err != nil {
 		return err
 	}
-	ctx := NewContext(a.ctx, a)
-	eg, ctx := errgroup.WithContext(ctx)
-	wg := sync.WaitGroup{}
-	for _, srv := range a.opts.servers {
-		srv := srv
-		eg.Go(func() error {
-			<-ctx.Done() // wait for stop signal
-			sctx, cancel := context.WithTimeout(NewContext(context.Background(), a), a.opts.stopTimeout)
-			defer cancel()
-			return srv.Stop(sctx)
-		})
-		wg.Add(1)
-		eg.Go(func() error {
-			wg.Done()
-			return srv.Start(ctx)
-		})
-	}
-	wg.Wait()
-	if a.opts.registrar != nil {
-		rctx, rcancel := context.WithTimeout(a.opts.ctx, a.opts.registrarTimeout)
-		defer rcancel()
-		if err := a.opts.registrar.Register(rctx, instance); err != nil {
-			return err
+	// the servers are not ready until the instance is registered.
+	a.setReady(false)
+	sctx := NewContext(a.ctx, a)
+	if err = runHooks(sctx, a.opts.beforeStart, false); err != nil {
+		return err
+	}
+	eg, ctx := errgroup.WithContext(sctx)
+	var (
+		mu       sync.Mutex
+		started  []*stage
+		stopping bool
+	)
+	eg.Go(func() error {
+		<-ctx.Done() // wait for stop signal
+		a.setReady(false)
+		a.beforeStop()
+		mu.Lock()
+		stopping = true
+		stages := started
+		mu.Unlock()
+		return a.stopStages(stages)
+	})
+	var readyTimeout <-chan time.Time
+	if a.opts.readyTimeout > 0 {
+		ready := time.NewTimer(a.opts.readyTimeout)
+		defer ready.Stop()
+		readyTimeout = ready.C
+	}
+	for _, st := range a.stages() {
+		mu.Lock()
+		if stopping {
+			mu.Unlock()
+			err = context.Canceled
+			break
+		}
+		started = append(started, st)
+		wg := sync.WaitGroup{}
+		for _, srv := range st.servers {
+			srv := srv
+			wg.Add(1)
+			eg.Go(func() error {
+				wg.Done()
+				return srv.Start(ctx)
+			})
+		}
+		mu.Unlock()
+		wg.Wait()
+		// the next stage starts once the servers of this one are ready.
+		if err = a.waitReady(ctx, readyTimeout, st.servers); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = a.register(instance)
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		// the app is stopped while starting, a failed server is reported by the errgroup.
+		err = nil
+	case err != nil:
+		// the servers are stopped before the startup error is returned.
+		a.opts.logger.Errorf("failed to start app: %v", err)
+		a.cancel()
+	default:
+		a.keepAlive(ctx, instance)
+		a.setReady(true)
+		if err = runHooks(sctx, a.opts.afterStart, false); err != nil {
+			a.opts.logger.Errorf("failed to run after start hooks: %v", err)
+			if serr := a.Stop(); serr != nil {
+				a.opts.logger.Errorf("failed to stop app: %v", serr)
+			}
 		}
-		a.lk.Lock()
-		a.instance = instance
-		a.lk.Unlock()
 	}
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, a.opts.sigs...)
@@ -128,30 +190,129 @@ func (a *App) Run() error {
 			}
 		}
 	})
-	if err := eg.Wait(); err != nil && !errors.Is(err, context.Canceled) {
-		return err
+	if werr := eg.Wait(); werr != nil && !errors.Is(werr, context.Canceled) && err == nil {
+		err = werr
 	}
-	return nil
+	a.keepAliveStop()
+	actx, cancel := a.stopContext()
+	defer cancel()
+	if herr := runHooks(actx, a.opts.afterStop, true); herr != nil && err == nil {
+		err = herr
+	}
+	return err
 }
 
 // Stop gracefully stops the application.
 func (a *App) Stop() error {
+	a.opts.logger.Info("app stopping")
+	a.setReady(false)
+	a.beforeStop()
 	a.lk.Lock()
 	instance := a.instance
 	a.lk.Unlock()
-	if a.opts.registrar != nil && instance != nil {
-		ctx, cancel := context.WithTimeout(a.opts.ctx, a.opts.registrarTimeout)
-		defer cancel()
-		if err := a.opts.registrar.Deregister(ctx, instance); err != nil {
+	// the keep-alive loops must not register the instance again.
+	a.keepAliveStop()
+	if instance != nil {
+		if err := a.deregister(instance); err != nil {
 			return err
 		}
+		a.opts.logger.Infof("app deregistered: %s", instance.ID)
 	}
+	a.drain()
+	a.opts.logger.Info("app stopping servers")
 	if a.cancel != nil {
 		a.cancel()
 	}
 	return nil
 }
 
+// waitReady waits for the servers implementing transport.Readier to be ready,
+// until the ready timeout fires, a nil timeout waits without a deadline.
+func (a *App) waitReady(ctx context.Context, timeout <-chan time.Time, servers []transport.Server) error {
+	for _, srv := range servers {
+		r, ok := srv.(transport.Readier)
+		if !ok {
+			continue
+		}
+		select {
+		case <-r.Ready():
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("server %T is not ready in %s", srv, a.opts.readyTimeout)
+		}
+	}
+	return nil
+}
+
+// drain waits for the drain period, while the servers keep serving
+// the requests of the clients not aware of the deregistration yet.
+func (a *App) drain() {
+	if a.opts.drainPeriod <= 0 {
+		return
+	}
+	a.opts.logger.Infof("app draining for %s, active requests: %d", a.opts.drainPeriod, a.activeRequests())
+	timer := time.NewTimer(a.opts.drainPeriod)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-a.ctx.Done():
+	}
+	a.opts.logger.Infof("app drained, active requests: %d", a.activeRequests())
+}
+
+// activeRequests returns the in-flight requests of the servers implementing transport.Tracker.
+func (a *App) activeRequests() (n int) {
+	for _, srv := range a.opts.servers {
+		if t, ok := srv.(transport.Tracker); ok {
+			n += t.ActiveRequests()
+		}
+	}
+	return n
+}
+
+// beforeStop runs the before stop hooks once, whether the app is stopped
+// by Stop or by a failed server.
+func (a *App) beforeStop() {
+	a.stopOnce.Do(func() {
+		ctx, cancel := a.stopContext()
+		defer cancel()
+		if err := runHooks(ctx, a.opts.beforeStop, true); err != nil {
+			a.opts.logger.Errorf("failed to run before stop hooks: %v", err)
+		}
+	})
+}
+
+// stopContext returns the context used to stop the app, bounded by the stop timeout.
+func (a *App) stopContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(NewContext(context.Background(), a), a.opts.stopTimeout)
+}
+
+// runHooks runs the hooks in order and returns the first error. The remaining
+// hooks are skipped after an error, unless all is true.
+func runHooks(ctx context.Context, hooks []func(context.Context) error, all bool) (err error) {
+	for _, fn := range hooks {
+		if herr := fn(ctx); herr != nil {
+			if !all {
+				return herr
+			}
+			if err == nil {
+				err = herr
+			}
+		}
+	}
+	return err
+}
+
+// setReady sets the readiness reported by the servers exposing the health of the app.
+func (a *App) setReady(ready bool) {
+	for _, srv := range a.opts.servers {
+		if r, ok := srv.(health.Reporter); ok && r.Health() != nil {
+			r.Health().SetReady(ready)
+		}
+	}
+}
+
 func (a *App) buildInstance() (*registry.ServiceInstance, error) {
 	endpoints := make([]string, 0) //nolint:gomnd
 	for _, e := range a.opts.endpoints {
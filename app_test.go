@@ -3,12 +3,15 @@ package kratos
 import (
 	"context"
 	"fmt"
+	nethttp "net/http"
 	"reflect"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/health"
 	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
 	"github.com/go-kratos/kratos/v2/transport/grpc"
 	"github.com/go-kratos/kratos/v2/transport/http"
 	"github.com/stretchr/testify/assert"
@@ -57,6 +60,257 @@ func TestApp(t *testing.T) {
 	}
 }
 
+func TestApp_Ready(t *testing.T) {
+	hs := http.NewServer(http.Health(health.New()))
+	app := New(
+		Name("kratos"),
+		Server(hs),
+		Registrar(&mockRegistry{service: make(map[string]*registry.ServiceInstance)}),
+		BeforeStart(func(context.Context) error {
+			assert.False(t, hs.Health().IsReady())
+			return nil
+		}),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	assert.Eventually(t, hs.Health().IsReady, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, app.Stop())
+	assert.False(t, hs.Health().IsReady())
+	assert.NoError(t, <-done)
+}
+
+type testServer struct {
+	startErr error
+	stopped  bool
+}
+
+func (s *testServer) Start(ctx context.Context) error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (s *testServer) Stop(ctx context.Context) error {
+	s.stopped = true
+	return nil
+}
+
+func TestApp_Hooks(t *testing.T) {
+	var (
+		lk    sync.Mutex
+		hooks []string
+	)
+	hook := func(name string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			_, ok := FromContext(ctx)
+			assert.True(t, ok)
+			lk.Lock()
+			defer lk.Unlock()
+			hooks = append(hooks, name)
+			return nil
+		}
+	}
+	var app *App
+	app = New(
+		Server(&testServer{}),
+		BeforeStart(hook("before start")),
+		AfterStart(hook("after start")),
+		AfterStart(func(ctx context.Context) error {
+			go func() { _ = app.Stop() }()
+			return nil
+		}),
+		BeforeStop(hook("before stop")),
+		AfterStop(hook("after stop")),
+	)
+	assert.NoError(t, app.Run())
+	assert.Equal(t, []string{"before start", "after start", "before stop", "after stop"}, hooks)
+}
+
+func TestApp_BeforeStartError(t *testing.T) {
+	srv := &testServer{}
+	want := fmt.Errorf("migration failed")
+	var stopped bool
+	app := New(
+		Server(srv),
+		BeforeStart(func(ctx context.Context) error { return want }),
+		AfterStop(func(ctx context.Context) error {
+			stopped = true
+			return nil
+		}),
+	)
+	assert.Equal(t, want, app.Run())
+	assert.False(t, srv.stopped)
+	assert.False(t, stopped)
+}
+
+func TestApp_StopHooksOnServerError(t *testing.T) {
+	want := fmt.Errorf("listen failed")
+	srv := &testServer{}
+	var hooks []string
+	app := New(
+		Server(srv, &testServer{startErr: want}),
+		BeforeStop(func(ctx context.Context) error {
+			hooks = append(hooks, "before stop")
+			return nil
+		}),
+		AfterStop(func(ctx context.Context) error {
+			hooks = append(hooks, "after stop")
+			return nil
+		}),
+	)
+	assert.Equal(t, want, app.Run())
+	assert.True(t, srv.stopped)
+	assert.Equal(t, []string{"before stop", "after stop"}, hooks)
+}
+
+type readyServer struct {
+	testServer
+	ready chan struct{}
+}
+
+func (s *readyServer) Ready() <-chan struct{} {
+	return s.ready
+}
+
+func TestApp_WaitReady(t *testing.T) {
+	srv := &readyServer{ready: make(chan struct{})}
+	r := &mockRegistry{service: make(map[string]*registry.ServiceInstance)}
+	var app *App
+	app = New(
+		Server(srv),
+		Registrar(r),
+		AfterStart(func(ctx context.Context) error {
+			go func() { _ = app.Stop() }()
+			return nil
+		}),
+	)
+	time.AfterFunc(100*time.Millisecond, func() {
+		r.lk.Lock()
+		defer r.lk.Unlock()
+		assert.Empty(t, r.service)
+		close(srv.ready)
+	})
+	assert.NoError(t, app.Run())
+	assert.NotNil(t, app.instance)
+}
+
+func TestApp_NotReady(t *testing.T) {
+	srv := &readyServer{ready: make(chan struct{})}
+	r := &mockRegistry{service: make(map[string]*registry.ServiceInstance)}
+	app := New(
+		Server(srv),
+		Registrar(r),
+		ReadyTimeout(100*time.Millisecond),
+	)
+	err := app.Run()
+	assert.Error(t, err)
+	assert.True(t, srv.stopped)
+	assert.Empty(t, r.service)
+}
+
+func TestApp_NoReadyTimeout(t *testing.T) {
+	srv := &readyServer{ready: make(chan struct{})}
+	var app *App
+	app = New(
+		Server(srv),
+		ReadyTimeout(0),
+		AfterStart(func(ctx context.Context) error {
+			go func() { _ = app.Stop() }()
+			return nil
+		}),
+	)
+	time.AfterFunc(50*time.Millisecond, func() { close(srv.ready) })
+	assert.NoError(t, app.Run())
+}
+
+func TestApp_Drain(t *testing.T) {
+	hs := http.NewServer(http.Address("127.0.0.1:0"), http.Health(health.New()))
+	release := make(chan struct{})
+	hs.HandleFunc("/slow", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		<-release
+	})
+	e, err := hs.Endpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string) int {
+		resp, err := nethttp.Get("http://" + e.Host + path)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	r := &mockRegistry{service: make(map[string]*registry.ServiceInstance)}
+	var (
+		app     *App
+		stopped = make(chan struct{})
+	)
+	app = New(
+		Server(hs),
+		Registrar(r),
+		DrainPeriod(time.Second),
+		AfterStart(func(ctx context.Context) error {
+			go get("/slow")
+			go func() {
+				defer close(stopped)
+				for hs.ActiveRequests() == 0 {
+					time.Sleep(10 * time.Millisecond)
+				}
+				_ = app.Stop()
+			}()
+			return nil
+		}),
+	)
+	time.AfterFunc(500*time.Millisecond, func() {
+		// Stop is draining, the instance is deregistered while the server keeps serving.
+		r.lk.Lock()
+		assert.Empty(t, r.service)
+		r.lk.Unlock()
+		assert.Equal(t, 1, hs.ActiveRequests())
+		assert.Equal(t, nethttp.StatusServiceUnavailable, get(health.ReadyPath))
+		assert.Equal(t, nethttp.StatusOK, get(health.LivePath))
+		select {
+		case <-stopped:
+			t.Error("app is stopped before the drain period")
+		default:
+		}
+		close(release)
+	})
+	assert.NoError(t, app.Run())
+}
+
+func TestApp_Registry(t *testing.T) {
+	r := memory.New()
+	w, err := r.Watch(context.Background(), "kratos")
+	assert.NoError(t, err)
+	defer w.Stop()
+	var app *App
+	app = New(
+		Name("kratos"),
+		Server(&testServer{}),
+		Registrar(r),
+		AfterStart(func(ctx context.Context) error {
+			go func() {
+				services, err := w.Next()
+				assert.NoError(t, err)
+				assert.Len(t, services, 1)
+				assert.Equal(t, app.ID(), services[0].ID)
+				_ = app.Stop()
+			}()
+			return nil
+		}),
+	)
+	assert.NoError(t, app.Run())
+	services, err := w.Next()
+	assert.NoError(t, err)
+	assert.Empty(t, services)
+}
+
 func TestApp_ID(t *testing.T) {
 	v := "123"
 	o := New(ID(v))
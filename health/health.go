@@ -0,0 +1,165 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// LivePath is the path of the liveness endpoint.
+	LivePath = "/healthz"
+	// ReadyPath is the path of the readiness endpoint.
+	ReadyPath = "/readyz"
+)
+
+// Status is the serving status.
+type Status string
+
+const (
+	// StatusServing reports the service is serving.
+	StatusServing Status = "SERVING"
+	// StatusNotServing reports the service is not serving.
+	StatusNotServing Status = "NOT_SERVING"
+)
+
+// Checker checks a dependency of the service, an error reports it unhealthy.
+type Checker func(ctx context.Context) error
+
+// Result is the result of a health check.
+type Result struct {
+	Status Status            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Serving returns whether the status is serving.
+func (r *Result) Serving() bool {
+	return r.Status == StatusServing
+}
+
+// Reporter is implemented by the servers exposing the health of the service,
+// Health returns nil when the server does not expose it.
+// kratos.App marks it ready once the instance is registered,
+// and not ready as soon as the app stops.
+type Reporter interface {
+	Health() *Health
+}
+
+// Option is health option.
+type Option func(*Health)
+
+// WithChecker with a dependency checker of the readiness.
+func WithChecker(name string, c Checker) Option {
+	return func(h *Health) {
+		h.checkers[name] = c
+	}
+}
+
+// WithTimeout with the timeout of the checkers, default is 1 second.
+func WithTimeout(timeout time.Duration) Option {
+	return func(h *Health) {
+		h.timeout = timeout
+	}
+}
+
+// Health reports the liveness and readiness of the service.
+type Health struct {
+	ready    int32
+	timeout  time.Duration
+	mu       sync.RWMutex
+	checkers map[string]Checker
+}
+
+// New creates a health which is ready, kratos.App marks it not ready
+// until the instance is registered.
+func New(opts ...Option) *Health {
+	h := &Health{
+		ready:    1,
+		timeout:  time.Second,
+		checkers: make(map[string]Checker),
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+// AddChecker adds a dependency checker of the readiness.
+func (h *Health) AddChecker(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers[name] = c
+}
+
+// SetReady sets whether the service is ready to serve traffic.
+func (h *Health) SetReady(ready bool) {
+	if ready {
+		atomic.StoreInt32(&h.ready, 1)
+	} else {
+		atomic.StoreInt32(&h.ready, 0)
+	}
+}
+
+// IsReady returns whether the service has been set ready.
+func (h *Health) IsReady() bool {
+	return atomic.LoadInt32(&h.ready) == 1
+}
+
+// Live reports the liveness of the service, which is serving as long as the process runs.
+func (h *Health) Live(ctx context.Context) *Result {
+	return &Result{Status: StatusServing}
+}
+
+// Ready reports the readiness of the service. It is serving when the service
+// has been set ready and all the checkers pass.
+func (h *Health) Ready(ctx context.Context) *Result {
+	if !h.IsReady() {
+		return &Result{Status: StatusNotServing}
+	}
+	h.mu.RLock()
+	checkers := make(map[string]Checker, len(h.checkers))
+	for name, c := range h.checkers {
+		checkers[name] = c
+	}
+	h.mu.RUnlock()
+	if len(checkers) == 0 {
+		return &Result{Status: StatusServing}
+	}
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	type check struct {
+		name string
+		err  error
+	}
+	// the buffered results let the checkers ignoring ctx finish without a reader.
+	checks := make(chan check, len(checkers))
+	for name, c := range checkers {
+		go func(name string, c Checker) {
+			checks <- check{name: name, err: c(ctx)}
+		}(name, c)
+	}
+	r := &Result{Status: StatusServing, Checks: make(map[string]string, len(checkers))}
+	for len(r.Checks) < len(checkers) {
+		select {
+		case c := <-checks:
+			r.Checks[c.name] = string(StatusServing)
+			if c.err != nil {
+				r.Status = StatusNotServing
+				r.Checks[c.name] = c.err.Error()
+			}
+		case <-ctx.Done():
+			// the checkers not finished in time are reported as timed out.
+			r.Status = StatusNotServing
+			for name := range checkers {
+				if _, ok := r.Checks[name]; !ok {
+					r.Checks[name] = ctx.Err().Error()
+				}
+			}
+		}
+	}
+	return r
+}
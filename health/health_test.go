@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	h := New(WithTimeout(100 * time.Millisecond))
+	assert.True(t, h.Live(context.Background()).Serving())
+	assert.True(t, h.IsReady())
+	h.SetReady(false)
+	assert.Equal(t, StatusNotServing, h.Ready(context.Background()).Status)
+
+	h.SetReady(true)
+	assert.True(t, h.IsReady())
+	assert.Equal(t, &Result{Status: StatusServing}, h.Ready(context.Background()))
+
+	h.AddChecker("db", func(ctx context.Context) error { return nil })
+	h.AddChecker("cache", func(ctx context.Context) error { return errors.New("cache is down") })
+	h.AddChecker("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	r := h.Ready(context.Background())
+	assert.Equal(t, StatusNotServing, r.Status)
+	assert.Equal(t, map[string]string{
+		"db":    string(StatusServing),
+		"cache": "cache is down",
+		"slow":  context.DeadlineExceeded.Error(),
+	}, r.Checks)
+
+	h.SetReady(false)
+	assert.False(t, h.Ready(context.Background()).Serving())
+}
+
+func TestWithChecker(t *testing.T) {
+	h := New(WithChecker("db", func(ctx context.Context) error { return nil }))
+	assert.Equal(t, map[string]string{"db": string(StatusServing)}, h.Ready(context.Background()).Checks)
+}
+
+func TestReadyBlockingChecker(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	h := New(WithTimeout(50*time.Millisecond),
+		WithChecker("db", func(ctx context.Context) error { return nil }),
+		// the checker ignores ctx and never returns in time.
+		WithChecker("blocking", func(ctx context.Context) error {
+			<-block
+			return nil
+		}),
+	)
+	start := time.Now()
+	r := h.Ready(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusNotServing, r.Status)
+	assert.Equal(t, map[string]string{
+		"db":       string(StatusServing),
+		"blocking": context.DeadlineExceeded.Error(),
+	}, r.Checks)
+}
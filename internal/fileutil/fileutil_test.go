@@ -0,0 +1,25 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot.json")
+	assert.NoError(t, WriteFile(path, []byte(`{"a":1}`)))
+	assert.NoError(t, WriteFile(path, []byte(`{}`)))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(data))
+	// the temporary files are removed.
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Error(t, WriteFile(filepath.Join(dir, "missing", "snapshot.json"), nil))
+}
@@ -0,0 +1,73 @@
+package jwtauth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+type authKey struct{}
+
+const (
+	// BearerWord the bearer key word for authorization
+	BearerWord string = "Bearer"
+
+	// AuthorizationKey holds the key used to store the JWT Token in the request tokenHeader.
+	AuthorizationKey string = "Authorization"
+
+	// Reason holds the error reason.
+	Reason string = "UNAUTHORIZED"
+)
+
+var (
+	ErrMissingJwtToken        = errors.Unauthorized(Reason, "JWT token is missing")
+	ErrMissingKeyFunc         = errors.Unauthorized(Reason, "keyFunc is missing")
+	ErrTokenInvalid           = errors.Unauthorized(Reason, "Token is invalid")
+	ErrTokenExpired           = errors.Unauthorized(Reason, "JWT token has expired")
+	ErrTokenParseFail         = errors.Unauthorized(Reason, "Fail to parse JWT token ")
+	ErrUnSupportSigningMethod = errors.Unauthorized(Reason, "Wrong signing method")
+)
+
+// Parse checks the bearer token carried by the authorization header value
+// and returns its claims.
+func Parse(authorization string, keyFunc jwt.Keyfunc, method jwt.SigningMethod) (jwt.Claims, error) {
+	if keyFunc == nil {
+		return nil, ErrMissingKeyFunc
+	}
+	auths := strings.SplitN(authorization, " ", 2)
+	if len(auths) != 2 || !strings.EqualFold(auths[0], BearerWord) {
+		return nil, ErrMissingJwtToken
+	}
+	tokenInfo, err := jwt.Parse(auths[1], keyFunc)
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok {
+			if ve.Errors&jwt.ValidationErrorMalformed != 0 {
+				return nil, ErrTokenInvalid
+			} else if ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0 {
+				return nil, ErrTokenExpired
+			} else {
+				return nil, ErrTokenParseFail
+			}
+		}
+		return nil, errors.Unauthorized(Reason, err.Error())
+	} else if !tokenInfo.Valid {
+		return nil, ErrTokenInvalid
+	} else if tokenInfo.Method != method {
+		return nil, ErrUnSupportSigningMethod
+	}
+	return tokenInfo.Claims, nil
+}
+
+// NewContext put auth info into context
+func NewContext(ctx context.Context, info jwt.Claims) context.Context {
+	return context.WithValue(ctx, authKey{}, info)
+}
+
+// FromContext extract auth info from context
+func FromContext(ctx context.Context) (token jwt.Claims, ok bool) {
+	token, ok = ctx.Value(authKey{}).(jwt.Claims)
+	return
+}
@@ -3,39 +3,37 @@ package jwt
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/golang-jwt/jwt/v4"
 
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/jwtauth"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport"
 )
 
-type authKey struct{}
-
 const (
 
 	// bearerWord the bearer key word for authorization
-	bearerWord string = "Bearer"
+	bearerWord string = jwtauth.BearerWord
 
 	// bearerFormat authorization token format
 	bearerFormat string = "Bearer %s"
 
 	// authorizationKey holds the key used to store the JWT Token in the request tokenHeader.
-	authorizationKey string = "Authorization"
+	authorizationKey string = jwtauth.AuthorizationKey
 
 	// reason holds the error reason.
-	reason string = "UNAUTHORIZED"
+	reason string = jwtauth.Reason
 )
 
 var (
-	ErrMissingJwtToken        = errors.Unauthorized(reason, "JWT token is missing")
-	ErrMissingKeyFunc         = errors.Unauthorized(reason, "keyFunc is missing")
-	ErrTokenInvalid           = errors.Unauthorized(reason, "Token is invalid")
-	ErrTokenExpired           = errors.Unauthorized(reason, "JWT token has expired")
-	ErrTokenParseFail         = errors.Unauthorized(reason, "Fail to parse JWT token ")
-	ErrUnSupportSigningMethod = errors.Unauthorized(reason, "Wrong signing method")
+	ErrMissingJwtToken        = jwtauth.ErrMissingJwtToken
+	ErrMissingKeyFunc         = jwtauth.ErrMissingKeyFunc
+	ErrTokenInvalid           = jwtauth.ErrTokenInvalid
+	ErrTokenExpired           = jwtauth.ErrTokenExpired
+	ErrTokenParseFail         = jwtauth.ErrTokenParseFail
+	ErrUnSupportSigningMethod = jwtauth.ErrUnSupportSigningMethod
 	ErrWrongContext           = errors.Unauthorized(reason, "Wrong context for middleware")
 	ErrNeedTokenProvider      = errors.Unauthorized(reason, "Token provider is missing")
 	ErrSignToken              = errors.Unauthorized(reason, "Can not sign token.Is the key correct?")
@@ -85,32 +83,11 @@ func Server(keyFunc jwt.Keyfunc, opts ...Option) middleware.Middleware {
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (interface{}, error) {
 			if header, ok := transport.FromServerContext(ctx); ok {
-				if keyFunc == nil {
-					return nil, ErrMissingKeyFunc
-				}
-				auths := strings.SplitN(header.RequestHeader().Get(authorizationKey), " ", 2)
-				if len(auths) != 2 || !strings.EqualFold(auths[0], bearerWord) {
-					return nil, ErrMissingJwtToken
-				}
-				jwtToken := auths[1]
-				tokenInfo, err := jwt.Parse(jwtToken, keyFunc)
+				claims, err := jwtauth.Parse(header.RequestHeader().Get(authorizationKey), keyFunc, o.signingMethod)
 				if err != nil {
-					if ve, ok := err.(*jwt.ValidationError); ok {
-						if ve.Errors&jwt.ValidationErrorMalformed != 0 {
-							return nil, ErrTokenInvalid
-						} else if ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0 {
-							return nil, ErrTokenExpired
-						} else {
-							return nil, ErrTokenParseFail
-						}
-					}
-					return nil, errors.Unauthorized(reason, err.Error())
-				} else if !tokenInfo.Valid {
-					return nil, ErrTokenInvalid
-				} else if tokenInfo.Method != o.signingMethod {
-					return nil, ErrUnSupportSigningMethod
+					return nil, err
 				}
-				ctx = NewContext(ctx, tokenInfo.Claims)
+				ctx = NewContext(ctx, claims)
 				return handler(ctx, req)
 			}
 			return nil, ErrWrongContext
@@ -157,11 +134,10 @@ func Client(keyProvider jwt.Keyfunc, opts ...Option) middleware.Middleware {
 
 // NewContext put auth info into context
 func NewContext(ctx context.Context, info jwt.Claims) context.Context {
-	return context.WithValue(ctx, authKey{}, info)
+	return jwtauth.NewContext(ctx, info)
 }
 
 // FromContext extract auth info from context
 func FromContext(ctx context.Context) (token jwt.Claims, ok bool) {
-	token, ok = ctx.Value(authKey{}).(jwt.Claims)
-	return
+	return jwtauth.FromContext(ctx)
 }
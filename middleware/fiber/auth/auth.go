@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/jwtauth"
+	"github.com/go-kratos/kratos/v2/transport/xhttp/apistate"
+)
+
+// ErrPermissionDenied is returned by the Authorizer when the policy rejects the request.
+var ErrPermissionDenied = errors.Forbidden("FORBIDDEN", "permission denied")
+
+// Option is authenticator option.
+type Option func(*Authenticator)
+
+// WithKeyFunc with the key func used to verify the JWT token.
+func WithKeyFunc(keyFunc jwt.Keyfunc) Option {
+	return func(a *Authenticator) {
+		a.keyFunc = keyFunc
+	}
+}
+
+// WithSigningMethod with signing method option, default is HS256.
+func WithSigningMethod(method jwt.SigningMethod) Option {
+	return func(a *Authenticator) {
+		a.signingMethod = method
+	}
+}
+
+// Authenticator checks the bearer JWT token of the request the same way
+// as the middleware/auth/jwt server middleware does.
+type Authenticator struct {
+	keyFunc       jwt.Keyfunc
+	signingMethod jwt.SigningMethod
+}
+
+// NewAuthenticator returns the Authenticator middleware.
+// Requests are rejected until a key func is given with WithKeyFunc.
+// The claims are stored in the user context and can be read with jwt.FromContext.
+func NewAuthenticator(opts ...Option) *Authenticator {
+	a := &Authenticator{
+		signingMethod: jwt.SigningMethodHS256,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// MiddlewareFunc returns the middleware function
+func (a *Authenticator) MiddlewareFunc() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := jwtauth.Parse(c.Get(jwtauth.AuthorizationKey), a.keyFunc, a.signingMethod)
+		if err != nil {
+			return apistate.Error[any]().WithError(err).Send(c)
+		}
+		c.SetUserContext(jwtauth.NewContext(c.UserContext(), claims))
+		return c.Next()
+	}
+}
+
+// Name returns the name of the middleware
+func (a *Authenticator) Name() string {
+	return "Authenticator"
+}
+
+// AuthorizerOption is authorizer option.
+type AuthorizerOption func(*Authorizer)
+
+// WithPolicy with the policy deciding whether the request is allowed.
+// The claims are nil when the request has not been authenticated.
+func WithPolicy(policy func(c *fiber.Ctx, claims jwt.Claims) bool) AuthorizerOption {
+	return func(a *Authorizer) {
+		a.policy = policy
+	}
+}
+
+// Authorizer checks the authenticated claims against a policy.
+type Authorizer struct {
+	policy func(c *fiber.Ctx, claims jwt.Claims) bool
+}
+
+// NewAuthorizer returns the Authorizer middleware,
+// which allows every request authenticated by the Authenticator by default.
+func NewAuthorizer(opts ...AuthorizerOption) *Authorizer {
+	a := &Authorizer{
+		policy: func(_ *fiber.Ctx, claims jwt.Claims) bool {
+			return claims != nil
+		},
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// MiddlewareFunc returns the middleware function
+func (a *Authorizer) MiddlewareFunc() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, _ := jwtauth.FromContext(c.UserContext())
+		if !a.policy(c, claims) {
+			return apistate.Error[any]().WithError(ErrPermissionDenied).Send(c)
+		}
+		return c.Next()
+	}
+}
+
+// Name returns the name of the middleware
+func (a *Authorizer) Name() string {
+	return "Authorizer"
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/internal/jwtauth"
+)
+
+func doRequest(t *testing.T, app *fiber.App, method, path string, header http.Header) (int, string, http.Header) {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body), res.Header
+}
+
+func TestAuth(t *testing.T) {
+	key := []byte("testKey")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "kratos"}).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFunc := func(*jwt.Token) (interface{}, error) { return key, nil }
+
+	newApp := func(auth *Authenticator, opts ...AuthorizerOption) *fiber.App {
+		app := fiber.New()
+		app.Use(auth.MiddlewareFunc(), NewAuthorizer(opts...).MiddlewareFunc())
+		app.Get("/", func(c *fiber.Ctx) error {
+			claims, ok := jwtauth.FromContext(c.UserContext())
+			assert.True(t, ok)
+			return c.SendString(claims.(jwt.MapClaims)["sub"].(string))
+		})
+		return app
+	}
+	bearer := http.Header{"Authorization": []string{"Bearer " + token}}
+
+	code, _, _ := doRequest(t, newApp(NewAuthenticator()), fiber.MethodGet, "/", bearer)
+	assert.Equal(t, fiber.StatusUnauthorized, code)
+
+	app := newApp(NewAuthenticator(WithKeyFunc(keyFunc)))
+	code, _, _ = doRequest(t, app, fiber.MethodGet, "/", nil)
+	assert.Equal(t, fiber.StatusUnauthorized, code)
+	code, body, _ := doRequest(t, app, fiber.MethodGet, "/", bearer)
+	assert.Equal(t, fiber.StatusOK, code)
+	assert.Equal(t, "kratos", body)
+
+	app = newApp(NewAuthenticator(WithKeyFunc(keyFunc)), WithPolicy(func(_ *fiber.Ctx, claims jwt.Claims) bool {
+		return claims.(jwt.MapClaims)["sub"] == "admin"
+	}))
+	code, _, _ = doRequest(t, app, fiber.MethodGet, "/", bearer)
+	assert.Equal(t, fiber.StatusForbidden, code)
+}
@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// cacheHeader reports whether the response was served from the cache.
+const cacheHeader = "X-Cache"
+
+// Option is cache option.
+type Option func(*Cache)
+
+// WithExpiration with the time a response stays in the cache, default is 1 minute.
+func WithExpiration(expiration time.Duration) Option {
+	return func(o *Cache) {
+		o.expiration = expiration
+	}
+}
+
+// WithKeyGenerator with the func generating the cache key of the request,
+// default is the request URL together with the Accept header.
+func WithKeyGenerator(keyGenerator func(c *fiber.Ctx) string) Option {
+	return func(o *Cache) {
+		o.keyGenerator = keyGenerator
+	}
+}
+
+// WithMaxEntries with the maximum number of the cached responses, default is 1024.
+// The response expiring first is evicted when the cache is full.
+func WithMaxEntries(n int) Option {
+	return func(o *Cache) {
+		o.maxEntries = n
+	}
+}
+
+type cacheEntry struct {
+	status      int
+	contentType []byte
+	body        []byte
+	expireAt    time.Time
+}
+
+// Cache keeps the successful GET and HEAD responses in an in-memory store
+// until they expire. The requests carrying the Authorization or Cookie header
+// and the responses marked private or no-store by Cache-Control are not cached.
+type Cache struct {
+	expiration   time.Duration
+	keyGenerator func(c *fiber.Ctx) string
+	maxEntries   int
+
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	lastSweep time.Time
+}
+
+// NewCache returns the Cache middleware.
+func NewCache(opts ...Option) *Cache {
+	c := &Cache{
+		expiration: time.Minute,
+		keyGenerator: func(c *fiber.Ctx) string {
+			return c.OriginalURL() + "|" + c.Get(fiber.HeaderAccept)
+		},
+		maxEntries: 1024,
+		entries:    make(map[string]*cacheEntry),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// MiddlewareFunc returns the middleware function
+func (m *Cache) MiddlewareFunc() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead || !cacheableRequest(c) {
+			return c.Next()
+		}
+		key := c.Method() + "|" + m.keyGenerator(c)
+		if e, ok := m.get(key); ok {
+			c.Set(cacheHeader, "hit")
+			c.Response().Header.SetContentTypeBytes(e.contentType)
+			c.Response().SetBody(e.body)
+			return c.SendStatus(e.status)
+		}
+		c.Set(cacheHeader, "miss")
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() != fiber.StatusOK || !cacheableResponse(c) {
+			return nil
+		}
+		m.set(key, &cacheEntry{
+			status:      c.Response().StatusCode(),
+			contentType: append([]byte(nil), c.Response().Header.ContentType()...),
+			body:        append([]byte(nil), c.Response().Body()...),
+		})
+		return nil
+	}
+}
+
+// Name returns the name of the middleware
+func (m *Cache) Name() string {
+	return "Cache"
+}
+
+// cacheableRequest reports whether the response of the request may be shared,
+// the requests carrying credentials are answered per user and never cached.
+func cacheableRequest(c *fiber.Ctx) bool {
+	if c.Get(fiber.HeaderAuthorization) != "" || c.Get(fiber.HeaderCookie) != "" {
+		return false
+	}
+	return !hasDirective(c.Get(fiber.HeaderCacheControl), "no-store")
+}
+
+// cacheableResponse reports whether the response may be stored in a shared cache.
+func cacheableResponse(c *fiber.Ctx) bool {
+	if len(c.Response().Header.Peek(fiber.HeaderSetCookie)) != 0 {
+		return false
+	}
+	cc := string(c.Response().Header.Peek(fiber.HeaderCacheControl))
+	return !hasDirective(cc, "no-store") && !hasDirective(cc, "private")
+}
+
+// hasDirective reports whether the Cache-Control header value contains the directive.
+func hasDirective(cacheControl, directive string) bool {
+	for _, d := range strings.Split(cacheControl, ",") {
+		d = strings.TrimSpace(d)
+		if i := strings.IndexByte(d, '='); i >= 0 {
+			d = d[:i]
+		}
+		if strings.EqualFold(d, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Cache) get(key string) (*cacheEntry, bool) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now, false)
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if now.After(e.expireAt) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return e, true
+}
+
+func (m *Cache) set(key string, e *cacheEntry) {
+	if m.maxEntries <= 0 {
+		return
+	}
+	now := time.Now()
+	e.expireAt = now.Add(m.expiration)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now, false)
+	if _, ok := m.entries[key]; !ok && len(m.entries) >= m.maxEntries {
+		m.sweep(now, true)
+		for len(m.entries) >= m.maxEntries {
+			m.evict()
+		}
+	}
+	m.entries[key] = e
+}
+
+// sweep drops the expired entries which are never read again, at most once
+// per expiration unless forced. It must be called with the lock held.
+func (m *Cache) sweep(now time.Time, force bool) {
+	if !force && now.Sub(m.lastSweep) <= m.expiration {
+		return
+	}
+	for k, v := range m.entries {
+		if now.After(v.expireAt) {
+			delete(m.entries, k)
+		}
+	}
+	m.lastSweep = now
+}
+
+// evict drops the entry expiring first. It must be called with the lock held.
+func (m *Cache) evict() {
+	var (
+		key   string
+		first time.Time
+	)
+	for k, v := range m.entries {
+		if key == "" || v.expireAt.Before(first) {
+			key, first = k, v.expireAt
+		}
+	}
+	delete(m.entries, key)
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func doRequest(t *testing.T, app *fiber.App, method, path string, header http.Header) (int, string, http.Header) {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body), res.Header
+}
+
+func TestCache(t *testing.T) {
+	var calls int
+	app := fiber.New()
+	app.Use(NewCache().MiddlewareFunc())
+	app.Get("/", func(c *fiber.Ctx) error {
+		calls++
+		return c.SendString(fmt.Sprintf("call-%d", calls))
+	})
+	app.Post("/", func(c *fiber.Ctx) error {
+		calls++
+		return c.SendString(fmt.Sprintf("call-%d", calls))
+	})
+
+	code, body, header := doRequest(t, app, fiber.MethodGet, "/", nil)
+	assert.Equal(t, fiber.StatusOK, code)
+	assert.Equal(t, "call-1", body)
+	assert.Equal(t, "miss", header.Get(cacheHeader))
+	_, body, header = doRequest(t, app, fiber.MethodGet, "/", nil)
+	assert.Equal(t, "call-1", body)
+	assert.Equal(t, "hit", header.Get(cacheHeader))
+	_, body, _ = doRequest(t, app, fiber.MethodPost, "/", nil)
+	assert.Equal(t, "call-2", body)
+
+	app = fiber.New()
+	app.Use(NewCache(WithExpiration(0)).MiddlewareFunc())
+	app.Get("/", func(c *fiber.Ctx) error {
+		calls++
+		return c.SendString(fmt.Sprintf("call-%d", calls))
+	})
+	_, body, _ = doRequest(t, app, fiber.MethodGet, "/", nil)
+	assert.Equal(t, "call-3", body)
+	_, body, _ = doRequest(t, app, fiber.MethodGet, "/", nil)
+	assert.Equal(t, "call-4", body)
+}
+
+func TestCachePrivate(t *testing.T) {
+	app := fiber.New()
+	app.Use(NewCache().MiddlewareFunc())
+	app.Get("/me", func(c *fiber.Ctx) error {
+		return c.SendString("profile of " + c.Get(fiber.HeaderAuthorization) + c.Cookies("user"))
+	})
+	app.Get("/private", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+		return c.SendString(c.Query("user"))
+	})
+	app.Get("/no-store", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.SendString(c.Get("X-User"))
+	})
+
+	_, body, _ := doRequest(t, app, fiber.MethodGet, "/me", http.Header{"Authorization": []string{"alice"}})
+	assert.Equal(t, "profile of alice", body)
+	_, body, _ = doRequest(t, app, fiber.MethodGet, "/me", http.Header{"Authorization": []string{"bob"}})
+	assert.Equal(t, "profile of bob", body)
+	_, body, _ = doRequest(t, app, fiber.MethodGet, "/me", http.Header{"Cookie": []string{"user=alice"}})
+	assert.Equal(t, "profile of alice", body)
+	_, body, _ = doRequest(t, app, fiber.MethodGet, "/me", http.Header{"Cookie": []string{"user=bob"}})
+	assert.Equal(t, "profile of bob", body)
+
+	_, body, _ = doRequest(t, app, fiber.MethodGet, "/no-store", http.Header{"X-User": []string{"alice"}})
+	assert.Equal(t, "alice", body)
+	_, body, header := doRequest(t, app, fiber.MethodGet, "/no-store", http.Header{"X-User": []string{"bob"}})
+	assert.Equal(t, "bob", body)
+	assert.Equal(t, "miss", header.Get(cacheHeader))
+
+	_, _, _ = doRequest(t, app, fiber.MethodGet, "/private", nil)
+	_, _, header = doRequest(t, app, fiber.MethodGet, "/private", nil)
+	assert.Equal(t, "miss", header.Get(cacheHeader))
+}
+
+func TestCacheMaxEntries(t *testing.T) {
+	var calls int
+	c := NewCache(WithMaxEntries(2))
+	app := fiber.New()
+	app.Use(c.MiddlewareFunc())
+	app.Get("/:id", func(c *fiber.Ctx) error {
+		calls++
+		return c.SendString(fmt.Sprintf("call-%d", calls))
+	})
+	for _, path := range []string{"/1", "/2", "/3"} {
+		doRequest(t, app, fiber.MethodGet, path, nil)
+		// the entries expire in order
+		time.Sleep(time.Millisecond)
+	}
+	assert.Len(t, c.entries, 2)
+	_, _, header := doRequest(t, app, fiber.MethodGet, "/1", nil)
+	assert.Equal(t, "miss", header.Get(cacheHeader))
+	_, _, header = doRequest(t, app, fiber.MethodGet, "/3", nil)
+	assert.Equal(t, "hit", header.Get(cacheHeader))
+	assert.Len(t, c.entries, 2)
+}
+
+func TestCacheSweepOnGet(t *testing.T) {
+	c := NewCache(WithExpiration(10 * time.Millisecond))
+	app := fiber.New()
+	app.Use(c.MiddlewareFunc())
+	app.Get("/:id", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	doRequest(t, app, fiber.MethodGet, "/1", nil)
+	doRequest(t, app, fiber.MethodGet, "/2", nil)
+	time.Sleep(20 * time.Millisecond)
+	// reading another response drops the expired ones.
+	c.get("/3")
+	assert.Empty(t, c.entries)
+}
@@ -0,0 +1,183 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/jwtauth"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// Option is logger option.
+type Option func(*Logger)
+
+// WithLogger with the logger the access log is written to.
+func WithLogger(l log.Logger) Option {
+	return func(o *Logger) {
+		o.logger = l
+	}
+}
+
+// Logger writes an access log entry for every request.
+type Logger struct {
+	logger log.Logger
+}
+
+// NewLogger returns the Logger middleware,
+// which logs to log.DefaultLogger by default.
+func NewLogger(opts ...Option) *Logger {
+	l := &Logger{
+		logger: log.DefaultLogger,
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	return l
+}
+
+// MiddlewareFunc returns the middleware function
+func (l *Logger) MiddlewareFunc() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startTime := time.Now()
+		err := c.Next()
+		level := log.LevelInfo
+		var errMsg string
+		if err != nil {
+			level = log.LevelError
+			errMsg = err.Error()
+		}
+		_ = log.WithContext(c.UserContext(), l.logger).Log(level,
+			"kind", "server",
+			"component", "fasthttp",
+			"method", c.Method(),
+			"path", c.Path(),
+			"route", c.Route().Path,
+			"ip", c.IP(),
+			"code", statusCode(c, err),
+			"error", errMsg,
+			"latency", time.Since(startTime).Seconds(),
+		)
+		return err
+	}
+}
+
+// Name returns the name of the middleware
+func (l *Logger) Name() string {
+	return "Logger"
+}
+
+// Operation is the record of an operation performed through the server.
+type Operation struct {
+	Operator string
+	Method   string
+	Path     string
+	IP       string
+	Code     int
+	Latency  time.Duration
+	Err      error
+}
+
+// OperationsOption is operations option.
+type OperationsOption func(*Operations)
+
+// WithRecorder with the recorder the operations are sent to,
+// default is logging them to log.DefaultLogger.
+func WithRecorder(recorder func(ctx context.Context, op *Operation)) OperationsOption {
+	return func(o *Operations) {
+		o.recorder = recorder
+	}
+}
+
+// WithMethods with the request methods recorded as operations,
+// default is POST, PUT, PATCH and DELETE.
+func WithMethods(methods ...string) OperationsOption {
+	return func(o *Operations) {
+		o.methods = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			o.methods[m] = struct{}{}
+		}
+	}
+}
+
+// Operations records the requests changing the state of the service,
+// together with the subject authenticated by the Authenticator.
+type Operations struct {
+	recorder func(ctx context.Context, op *Operation)
+	methods  map[string]struct{}
+}
+
+// NewOperations returns the Operations middleware.
+func NewOperations(opts ...OperationsOption) *Operations {
+	o := &Operations{
+		recorder: logOperation,
+		methods: map[string]struct{}{
+			fiber.MethodPost:   {},
+			fiber.MethodPut:    {},
+			fiber.MethodPatch:  {},
+			fiber.MethodDelete: {},
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// MiddlewareFunc returns the middleware function
+func (o *Operations) MiddlewareFunc() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := o.methods[c.Method()]; !ok {
+			return c.Next()
+		}
+		startTime := time.Now()
+		err := c.Next()
+		op := &Operation{
+			Method:  c.Method(),
+			Path:    c.Path(),
+			IP:      c.IP(),
+			Code:    statusCode(c, err),
+			Latency: time.Since(startTime),
+			Err:     err,
+		}
+		if claims, ok := jwtauth.FromContext(c.UserContext()); ok {
+			if mc, ok := claims.(jwt.MapClaims); ok {
+				op.Operator, _ = mc["sub"].(string)
+			}
+		}
+		o.recorder(c.UserContext(), op)
+		return err
+	}
+}
+
+// Name returns the name of the middleware
+func (o *Operations) Name() string {
+	return "Operations"
+}
+
+func logOperation(ctx context.Context, op *Operation) {
+	_ = log.WithContext(ctx, log.DefaultLogger).Log(log.LevelInfo,
+		"kind", "operation",
+		"operator", op.Operator,
+		"method", op.Method,
+		"path", op.Path,
+		"ip", op.IP,
+		"code", op.Code,
+		"latency", op.Latency.Seconds(),
+	)
+}
+
+// statusCode returns the status code the request is answered with,
+// including the code of the error the error handler will send.
+func statusCode(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	if fe, ok := err.(*fiber.Error); ok {
+		return fe.Code
+	}
+	return int(errors.FromError(err).Code)
+}
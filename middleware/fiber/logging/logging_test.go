@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/internal/jwtauth"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func doRequest(t *testing.T, app *fiber.App, method, path string, header http.Header) (int, string, http.Header) {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body), res.Header
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	app := fiber.New()
+	app.Use(NewLogger(WithLogger(log.NewStdLogger(&buf))).MiddlewareFunc())
+	app.Get("/users/:id", func(c *fiber.Ctx) error { return fiber.ErrNotFound })
+	code, _, _ := doRequest(t, app, fiber.MethodGet, "/users/1", nil)
+	assert.Equal(t, fiber.StatusNotFound, code)
+	assert.True(t, strings.HasPrefix(buf.String(), "ERROR"))
+	assert.Contains(t, buf.String(), "route=/users/:id")
+	assert.Contains(t, buf.String(), "code=404")
+}
+
+func TestOperations(t *testing.T) {
+	var ops []*Operation
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(jwtauth.NewContext(c.UserContext(), jwt.MapClaims{"sub": "kratos"}))
+		return c.Next()
+	})
+	app.Use(NewOperations(WithRecorder(func(_ context.Context, op *Operation) {
+		ops = append(ops, op)
+	})).MiddlewareFunc())
+	app.All("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	doRequest(t, app, fiber.MethodGet, "/", nil)
+	doRequest(t, app, fiber.MethodDelete, "/", nil)
+	assert.Equal(t, 1, len(ops))
+	assert.Equal(t, "kratos", ops[0].Operator)
+	assert.Equal(t, fiber.MethodDelete, ops[0].Method)
+	assert.Equal(t, fiber.StatusNoContent, ops[0].Code)
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"github.com/go-kratos/aegis/ratelimit"
+	"github.com/go-kratos/aegis/ratelimit/bbr"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport/xhttp/apistate"
+)
+
+// ErrLimitExceed is service unavailable due to rate limit exceeded.
+var ErrLimitExceed = errors.New(429, "RATELIMIT", "service unavailable due to rate limit exceeded")
+
+// Option is limiter option.
+type Option func(*Limiter)
+
+// WithLimiter set Limiter implementation,
+// default is bbr limiter
+func WithLimiter(l ratelimit.Limiter) Option {
+	return func(o *Limiter) {
+		o.limiter = l
+	}
+}
+
+// Limiter rejects the requests once the limiter does not allow them.
+type Limiter struct {
+	limiter ratelimit.Limiter
+}
+
+// NewLimiter returns the Limiter middleware.
+func NewLimiter(opts ...Option) *Limiter {
+	l := &Limiter{}
+	for _, o := range opts {
+		o(l)
+	}
+	if l.limiter == nil {
+		l.limiter = bbr.NewLimiter()
+	}
+	return l
+}
+
+// MiddlewareFunc returns the middleware function
+func (l *Limiter) MiddlewareFunc() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		done, err := l.limiter.Allow()
+		if err != nil {
+			// rejected
+			return apistate.Error[any]().WithError(ErrLimitExceed).Send(c)
+		}
+		// allowed
+		err = c.Next()
+		done(ratelimit.DoneInfo{Err: err})
+		return err
+	}
+}
+
+// Name returns the name of the middleware
+func (l *Limiter) Name() string {
+	return "Limiter"
+}
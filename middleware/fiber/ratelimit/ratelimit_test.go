@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kratos/aegis/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func doRequest(t *testing.T, app *fiber.App, method, path string, header http.Header) (int, string, http.Header) {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body), res.Header
+}
+
+type rejectLimiter struct{}
+
+func (rejectLimiter) Allow() (ratelimit.DoneFunc, error) {
+	return nil, errors.New("rejected")
+}
+
+func TestLimiter(t *testing.T) {
+	app := fiber.New()
+	app.Use(NewLimiter().MiddlewareFunc())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	code, body, _ := doRequest(t, app, fiber.MethodGet, "/", nil)
+	assert.Equal(t, fiber.StatusOK, code)
+	assert.Equal(t, "ok", body)
+
+	app = fiber.New()
+	app.Use(NewLimiter(WithLimiter(rejectLimiter{})).MiddlewareFunc())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	code, body, _ = doRequest(t, app, fiber.MethodGet, "/", nil)
+	assert.Equal(t, fiber.StatusTooManyRequests, code)
+	assert.Contains(t, body, "RATELIMIT")
+}
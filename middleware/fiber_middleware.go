@@ -1,6 +1,12 @@
 package middleware
 
 import (
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/middleware/fiber/auth"
+	"github.com/go-kratos/kratos/v2/middleware/fiber/cache"
+	"github.com/go-kratos/kratos/v2/middleware/fiber/logging"
+	"github.com/go-kratos/kratos/v2/middleware/fiber/ratelimit"
 	"github.com/go-kratos/kratos/v2/transport/xhttp/apistate"
 	"github.com/gofiber/fiber/v2"
 )
@@ -16,14 +22,21 @@ const (
 	LimiterCfg               = "Limiter"
 )
 
-var middlewareConf = map[string]FiberMiddleware{
-	AuthenticatorCfg: defaultMiddleware(),
-	AuthorizerCfg:    defaultMiddleware(),
-	OperationsCfg:    defaultMiddleware(),
-	LoggerCfg:        defaultMiddleware(),
-	CacheCfg:         defaultMiddleware(),
-	LimiterCfg:       defaultMiddleware(),
-}
+var (
+	mu             sync.RWMutex
+	middlewareConf = map[string]FiberMiddleware{
+		AuthenticatorCfg: auth.NewAuthenticator(),
+		AuthorizerCfg:    auth.NewAuthorizer(),
+		OperationsCfg:    logging.NewOperations(),
+		LoggerCfg:        logging.NewLogger(),
+		CacheCfg:         cache.NewCache(),
+	}
+	// lazyMiddleware builds the default middlewares on their first use,
+	// so that the bbr limiter is only created by the services limiting requests.
+	lazyMiddleware = map[string]func() FiberMiddleware{
+		LimiterCfg: func() FiberMiddleware { return ratelimit.NewLimiter() },
+	}
+)
 
 // FiberMiddleware is a middleware for Fiber
 type FiberMiddleware interface {
@@ -52,67 +65,70 @@ func defaultMiddleware() *UnimplementedMiddleware {
 	return &UnimplementedMiddleware{}
 }
 
-// RegisterMiddleware registers a middleware
+// RegisterMiddleware registers a middleware, replacing the one with the same name.
+// It is safe for concurrent use.
 func RegisterMiddleware(mw FiberMiddleware) {
+	mu.Lock()
+	defer mu.Unlock()
 	if middlewareConf == nil {
 		middlewareConf = make(map[string]FiberMiddleware)
 	}
 	middlewareConf[mw.Name()] = mw
 }
 
-// Authenticator returns the Authenticator middleware
-func Authenticator() fiber.Handler {
-	if mw, ok := middlewareConf[AuthenticatorCfg]; ok {
+// lookup returns the middleware function registered with name, registering
+// the default or the unimplemented middleware when it is missing.
+func lookup(name string) fiber.Handler {
+	mu.RLock()
+	mw, ok := middlewareConf[name]
+	mu.RUnlock()
+	if ok {
 		return mw.MiddlewareFunc()
 	}
-	return middlewareConf[AuthenticatorCfg].MiddlewareFunc()
+	mu.Lock()
+	defer mu.Unlock()
+	if mw, ok = middlewareConf[name]; !ok {
+		if newMiddleware, ok := lazyMiddleware[name]; ok {
+			mw = newMiddleware()
+		} else {
+			mw = defaultMiddleware()
+		}
+		middlewareConf[name] = mw
+	}
+	return mw.MiddlewareFunc()
+}
+
+// Authenticator returns the Authenticator middleware
+func Authenticator() fiber.Handler {
+	return lookup(AuthenticatorCfg)
 }
 
 // Authorizer returns the Authorizer middleware
 func Authorizer() fiber.Handler {
-	if mw, ok := middlewareConf[AuthorizerCfg]; ok {
-		return mw.MiddlewareFunc()
-	}
-	return middlewareConf[AuthorizerCfg].MiddlewareFunc()
+	return lookup(AuthorizerCfg)
 }
 
 // Cache returns the Cache middleware
 func Cache() fiber.Handler {
-	if mw, ok := middlewareConf[CacheCfg]; ok {
-		return mw.MiddlewareFunc()
-	}
-	return middlewareConf[CacheCfg].MiddlewareFunc()
+	return lookup(CacheCfg)
 }
 
-// Limiter returns the Limiter middleware
+// Limiter returns the Limiter middleware, the bbr limiter by default.
 func Limiter() fiber.Handler {
-	if mw, ok := middlewareConf[LimiterCfg]; ok {
-		return mw.MiddlewareFunc()
-	}
-	return middlewareConf[LimiterCfg].MiddlewareFunc()
+	return lookup(LimiterCfg)
 }
 
 // Operations returns the Operations middleware
 func Operations() fiber.Handler {
-	if mw, ok := middlewareConf[OperationsCfg]; ok {
-		return mw.MiddlewareFunc()
-	}
-	return middlewareConf[OperationsCfg].MiddlewareFunc()
+	return lookup(OperationsCfg)
 }
 
 // Logger returns the Logger middleware
 func Logger() fiber.Handler {
-	if mw, ok := middlewareConf[LoggerCfg]; ok {
-		return mw.MiddlewareFunc()
-	}
-	return middlewareConf[LoggerCfg].MiddlewareFunc()
+	return lookup(LoggerCfg)
 }
 
 // CustomMiddleware returns a custom middleware with your config key
 func CustomMiddleware(name string) fiber.Handler {
-	if mw, ok := middlewareConf[name]; ok {
-		return mw.MiddlewareFunc()
-	}
-	middlewareConf[name] = defaultMiddleware()
-	return middlewareConf[name].MiddlewareFunc()
+	return lookup(name)
 }
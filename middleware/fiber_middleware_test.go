@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type namedMiddleware struct {
+	UnimplementedMiddleware
+	name string
+}
+
+func (m *namedMiddleware) Name() string {
+	return m.name
+}
+
+func TestRegisterMiddleware(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("custom-%d", i%10)
+			RegisterMiddleware(&namedMiddleware{name: name})
+			assert.NotNil(t, CustomMiddleware(name))
+		}(i)
+	}
+	wg.Wait()
+	for _, h := range []fiber.Handler{Authenticator(), Authorizer(), Cache(), Limiter(), Operations(), Logger()} {
+		assert.NotNil(t, h)
+	}
+}
+
+func TestDefaultMiddleware(t *testing.T) {
+	for _, name := range []string{AuthenticatorCfg, AuthorizerCfg, OperationsCfg, LoggerCfg, CacheCfg} {
+		mu.RLock()
+		mw := middlewareConf[name]
+		mu.RUnlock()
+		assert.Equal(t, name, mw.Name())
+	}
+}
+
+func TestDefaultLimiter(t *testing.T) {
+	app := fiber.New()
+	app.Use(Limiter())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	mu.RLock()
+	assert.Equal(t, LimiterCfg, middlewareConf[LimiterCfg].Name())
+	mu.RUnlock()
+}
@@ -12,6 +12,10 @@ import (
 type (
 	transporter func(ctx context.Context) (transport.Transporter, bool)
 	MatchFunc   func(ctx context.Context, operation string) bool
+
+	pathTemplater interface {
+		PathTemplate() string
+	}
 )
 
 var (
@@ -89,7 +93,21 @@ func (b *Builder) matchs(ctx context.Context, transporter transporter) bool {
 		return false
 	}
 
-	operation := info.Operation()
+	operations := []string{info.Operation()}
+	// fiber routes are matched by the route path as well, for example: /users/:id
+	if tr, ok := info.(pathTemplater); ok && info.Kind() == transport.KindXHTTP && tr.PathTemplate() != info.Operation() {
+		operations = append(operations, tr.PathTemplate())
+	}
+	for _, operation := range operations {
+		if b.matchOperation(ctx, operation) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOperation is match one operation compliance Builder
+func (b *Builder) matchOperation(ctx context.Context, operation string) bool {
 	for _, prefix := range b.prefix {
 		if prefixMatch(prefix, operation) {
 			return true
@@ -241,3 +241,43 @@ func testMiddleware(handler middleware.Handler) middleware.Handler {
 		return
 	}
 }
+
+type templateTransport struct {
+	Transport
+	pathTemplate string
+}
+
+func (tr *templateTransport) PathTemplate() string {
+	return tr.pathTemplate
+}
+
+func TestMatchPathTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		kind transport.Kind
+		want bool
+	}{
+		{name: "fasthttp", kind: transport.KindXHTTP, want: true},
+		{name: "http", kind: transport.KindHTTP, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var matched bool
+			m := func(handler middleware.Handler) middleware.Handler {
+				return func(ctx context.Context, req interface{}) (interface{}, error) {
+					matched = true
+					return handler(ctx, req)
+				}
+			}
+			next := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "reply", nil
+			}
+			ctx := transport.NewServerContext(context.Background(), &templateTransport{
+				Transport:    Transport{kind: test.kind, operation: "/users/1"},
+				pathTemplate: "/users/:id",
+			})
+			_, _ = Server(m).Path("/users/:id").Build()(next)(ctx, nil)
+			assert.Equal(t, test.want, matched)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/transport"
 )
@@ -25,11 +26,23 @@ type options struct {
 	ctx  context.Context
 	sigs []os.Signal
 
-	logger           *log.Helper
-	registrar        registry.Registrar
-	registrarTimeout time.Duration
-	stopTimeout      time.Duration
-	servers          []transport.Server
+	logger            *log.Helper
+	registrars        []registry.Registrar
+	registrarTimeout  time.Duration
+	registrarInterval time.Duration
+	registrarCounter  metrics.Counter
+	readyTimeout      time.Duration
+	drainPeriod       time.Duration
+	stopTimeout       time.Duration
+	stageTimeout      time.Duration
+	servers           []transport.Server
+	staged            []stagedServer
+
+	// Before and After funcs
+	beforeStart []func(context.Context) error
+	beforeStop  []func(context.Context) error
+	afterStart  []func(context.Context) error
+	afterStop   []func(context.Context) error
 }
 
 // ID with service id.
@@ -74,14 +87,31 @@ func Server(srv ...transport.Server) Option {
 	return func(o *options) { o.servers = srv }
 }
 
+// Stage with transport servers started in the given stage. The stages start in
+// ascending order, each one once the servers of the previous one are ready,
+// and stop in descending order. The servers of Server are in stage 0, unless
+// they are passed to Stage too, and a server is only in the first stage it is passed to.
+func Stage(stage int, srv ...transport.Server) Option {
+	return func(o *options) {
+		for _, s := range srv {
+			o.staged = append(o.staged, stagedServer{stage: stage, srv: s})
+		}
+	}
+}
+
+// StageTimeout with the timeout stopping each stage, bounded by the stop timeout.
+func StageTimeout(t time.Duration) Option {
+	return func(o *options) { o.stageTimeout = t }
+}
+
 // Signal with exit signals.
 func Signal(sigs ...os.Signal) Option {
 	return func(o *options) { o.sigs = sigs }
 }
 
-// Registrar with service registry.
-func Registrar(r registry.Registrar) Option {
-	return func(o *options) { o.registrar = r }
+// Registrar with service registries, the instance is registered to all of them.
+func Registrar(r ...registry.Registrar) Option {
+	return func(o *options) { o.registrars = r }
 }
 
 // RegistrarTimeout with registrar timeout.
@@ -89,7 +119,60 @@ func RegistrarTimeout(t time.Duration) Option {
 	return func(o *options) { o.registrarTimeout = t }
 }
 
+// ReadyTimeout with the timeout waiting for the servers to be ready, default is 10 seconds.
+// The app fails to start if a server is not ready in time, a timeout <= 0 waits without a deadline.
+func ReadyTimeout(t time.Duration) Option {
+	return func(o *options) { o.readyTimeout = t }
+}
+
+// DrainPeriod with the period between the deregistration and the stop of the servers,
+// which keep serving while the clients catch up with the deregistration.
+func DrainPeriod(d time.Duration) Option {
+	return func(o *options) { o.drainPeriod = d }
+}
+
+// RegistrarInterval with the interval keeping the instance registered, disabled by default.
+// The registration is refreshed when the registrar implements registry.Refresher,
+// or registered again otherwise. The failed attempts are retried with backoff.
+func RegistrarInterval(t time.Duration) Option {
+	return func(o *options) { o.registrarInterval = t }
+}
+
+// RegistrarCounter with the counter of the keep-alive attempts,
+// labeled with the registrar, the operation and the result.
+func RegistrarCounter(c metrics.Counter) Option {
+	return func(o *options) { o.registrarCounter = c }
+}
+
 // StopTimeout with app stop timeout.
 func StopTimeout(t time.Duration) Option {
 	return func(o *options) { o.stopTimeout = t }
 }
+
+// BeforeStart run funcs before app starts, a failed func aborts the startup.
+func BeforeStart(fn func(context.Context) error) Option {
+	return func(o *options) {
+		o.beforeStart = append(o.beforeStart, fn)
+	}
+}
+
+// BeforeStop run funcs before app stops, even when a server has failed.
+func BeforeStop(fn func(context.Context) error) Option {
+	return func(o *options) {
+		o.beforeStop = append(o.beforeStop, fn)
+	}
+}
+
+// AfterStart run funcs after app starts, a failed func stops the app.
+func AfterStart(fn func(context.Context) error) Option {
+	return func(o *options) {
+		o.afterStart = append(o.afterStart, fn)
+	}
+}
+
+// AfterStop run funcs after app stops, even when a server has failed.
+func AfterStop(fn func(context.Context) error) Option {
+	return func(o *options) {
+		o.afterStop = append(o.afterStop, fn)
+	}
+}
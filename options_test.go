@@ -112,7 +112,7 @@ func TestRegistrar(t *testing.T) {
 	o := &options{}
 	v := &mockRegistrar{}
 	Registrar(v)(o)
-	assert.Equal(t, v, o.registrar)
+	assert.Equal(t, []registry.Registrar{v}, o.registrars)
 }
 
 func TestRegistrarTimeout(t *testing.T) {
@@ -121,3 +121,45 @@ func TestRegistrarTimeout(t *testing.T) {
 	RegistrarTimeout(v)(o)
 	assert.Equal(t, v, o.registrarTimeout)
 }
+
+func TestRegistrarInterval(t *testing.T) {
+	o := &options{}
+	v := time.Duration(123)
+	RegistrarInterval(v)(o)
+	assert.Equal(t, v, o.registrarInterval)
+}
+
+func TestRegistrarCounter(t *testing.T) {
+	o := &options{}
+	v := &mockCounter{}
+	RegistrarCounter(v)(o)
+	assert.Equal(t, v, o.registrarCounter)
+}
+
+func TestReadyTimeout(t *testing.T) {
+	o := &options{}
+	v := time.Duration(123)
+	ReadyTimeout(v)(o)
+	assert.Equal(t, v, o.readyTimeout)
+}
+
+func TestDrainPeriod(t *testing.T) {
+	o := &options{}
+	v := time.Duration(123)
+	DrainPeriod(v)(o)
+	assert.Equal(t, v, o.drainPeriod)
+}
+
+func TestHooks(t *testing.T) {
+	o := &options{}
+	fn := func(ctx context.Context) error { return nil }
+	BeforeStart(fn)(o)
+	BeforeStart(fn)(o)
+	AfterStart(fn)(o)
+	BeforeStop(fn)(o)
+	AfterStop(fn)(o)
+	assert.Equal(t, 2, len(o.beforeStart))
+	assert.Equal(t, 1, len(o.afterStart))
+	assert.Equal(t, 1, len(o.beforeStop))
+	assert.Equal(t, 1, len(o.afterStop))
+}
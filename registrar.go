@@ -0,0 +1,163 @@
+package kratos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+const (
+	opRegister = "register"
+	opRefresh  = "refresh"
+
+	// minBackoff is the first delay retrying a failed keep-alive attempt.
+	minBackoff = time.Second
+)
+
+// register registers the instance to the registrars. When a registrar fails,
+// the instance is deregistered from the ones already registered to.
+func (a *App) register(instance *registry.ServiceInstance) error {
+	for i, r := range a.opts.registrars {
+		ctx, cancel := context.WithTimeout(a.opts.ctx, a.opts.registrarTimeout)
+		err := r.Register(ctx, instance)
+		cancel()
+		if err != nil {
+			for _, r := range a.opts.registrars[:i] {
+				ctx, cancel := context.WithTimeout(a.opts.ctx, a.opts.registrarTimeout)
+				if derr := r.Deregister(ctx, instance); derr != nil {
+					a.opts.logger.Errorf("failed to deregister instance %s from %T: %v", instance.ID, r, derr)
+				}
+				cancel()
+			}
+			return err
+		}
+	}
+	if len(a.opts.registrars) > 0 {
+		a.lk.Lock()
+		a.instance = instance
+		a.lk.Unlock()
+	}
+	return nil
+}
+
+// deregister deregisters the instance from all the registrars, and returns the first error.
+func (a *App) deregister(instance *registry.ServiceInstance) (err error) {
+	for _, r := range a.opts.registrars {
+		ctx, cancel := context.WithTimeout(a.opts.ctx, a.opts.registrarTimeout)
+		if derr := r.Deregister(ctx, instance); derr != nil {
+			a.opts.logger.Errorf("failed to deregister instance %s from %T: %v", instance.ID, r, derr)
+			if err == nil {
+				err = derr
+			}
+		}
+		cancel()
+	}
+	return err
+}
+
+// keepAlive starts a loop per registrar keeping the instance registered,
+// until ctx is done or keepAliveStop is called.
+func (a *App) keepAlive(ctx context.Context, instance *registry.ServiceInstance) {
+	if a.opts.registrarInterval <= 0 || len(a.opts.registrars) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, r := range a.opts.registrars {
+		wg.Add(1)
+		go func(r registry.Registrar) {
+			defer wg.Done()
+			a.keepAliveLoop(ctx, r, instance)
+		}(r)
+	}
+	a.lk.Lock()
+	a.stopKeepAlive = func() {
+		cancel()
+		wg.Wait()
+	}
+	a.lk.Unlock()
+}
+
+// keepAliveStop stops the keep-alive loops and waits for them to return.
+func (a *App) keepAliveStop() {
+	a.lk.Lock()
+	stop := a.stopKeepAlive
+	a.lk.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+func (a *App) keepAliveLoop(ctx context.Context, r registry.Registrar, instance *registry.ServiceInstance) {
+	failures := 0
+	timer := time.NewTimer(a.opts.registrarInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		delay := a.opts.registrarInterval
+		if err := a.renew(ctx, r, instance); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			delay = backoff(failures, a.opts.registrarInterval)
+			a.opts.logger.Errorf("failed to keep instance %s registered to %T: %v, retry in %s", instance.ID, r, err, delay)
+		} else if failures > 0 {
+			failures = 0
+			a.opts.logger.Infof("instance %s registered to %T again", instance.ID, r)
+		}
+		timer.Reset(delay)
+	}
+}
+
+// renew refreshes the registration when the registrar implements registry.Refresher,
+// the instance is registered again when it is not supported or the registration is lost.
+func (a *App) renew(ctx context.Context, r registry.Registrar, instance *registry.ServiceInstance) error {
+	if f, ok := r.(registry.Refresher); ok {
+		err := a.attempt(ctx, r, opRefresh, func(ctx context.Context) error {
+			return f.Refresh(ctx, instance)
+		})
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		a.opts.logger.Warnf("registration of instance %s to %T is lost: %v", instance.ID, r, err)
+	}
+	return a.attempt(ctx, r, opRegister, func(ctx context.Context) error {
+		return r.Register(ctx, instance)
+	})
+}
+
+// attempt runs a keep-alive operation bounded by the registrar timeout, and counts its result.
+func (a *App) attempt(ctx context.Context, r registry.Registrar, op string, fn func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, a.opts.registrarTimeout)
+	defer cancel()
+	err := fn(ctx)
+	if a.opts.registrarCounter != nil {
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+		a.opts.registrarCounter.With(fmt.Sprintf("%T", r), op, result).Inc()
+	}
+	return err
+}
+
+// backoff returns the delay before the next attempt, doubled on each
+// consecutive failure and bounded by the keep-alive interval.
+func backoff(failures int, interval time.Duration) time.Duration {
+	delay := minBackoff
+	for i := 1; i < failures && delay < interval; i++ {
+		delay *= 2
+	}
+	if delay > interval {
+		delay = interval
+	}
+	return delay
+}
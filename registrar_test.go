@@ -0,0 +1,114 @@
+package kratos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockCounter struct {
+	lk     sync.Mutex
+	lvs    []string
+	counts map[string]int
+}
+
+func (c *mockCounter) With(lvs ...string) metrics.Counter {
+	return &mockCounter{lvs: lvs, counts: c.counts}
+}
+
+func (c *mockCounter) Inc() {
+	c.Add(1)
+}
+
+func (c *mockCounter) Add(delta float64) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	c.counts[strings.Join(c.lvs, ",")] += int(delta)
+}
+
+type refreshRegistry struct {
+	mockRegistry
+	refreshed int
+}
+
+func (r *refreshRegistry) Refresh(ctx context.Context, service *registry.ServiceInstance) error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if r.service[service.ID] == nil {
+		return fmt.Errorf("service not found")
+	}
+	r.refreshed++
+	return nil
+}
+
+func (r *refreshRegistry) lose(id string) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	delete(r.service, id)
+}
+
+func (r *refreshRegistry) registered(id string) bool {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	return r.service[id] != nil
+}
+
+func TestApp_KeepAlive(t *testing.T) {
+	r1 := &mockRegistry{service: make(map[string]*registry.ServiceInstance)}
+	r2 := &refreshRegistry{mockRegistry: mockRegistry{service: make(map[string]*registry.ServiceInstance)}}
+	counter := &mockCounter{counts: make(map[string]int)}
+	var app *App
+	app = New(
+		ID("kratos"),
+		Server(&testServer{}),
+		Registrar(r1, r2),
+		RegistrarInterval(20*time.Millisecond),
+		RegistrarCounter(counter),
+		AfterStart(func(ctx context.Context) error {
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				r2.lose("kratos")
+				time.Sleep(50 * time.Millisecond)
+				assert.True(t, r2.registered("kratos"))
+				_ = app.Stop()
+			}()
+			return nil
+		}),
+	)
+	assert.NoError(t, app.Run())
+	assert.Empty(t, r1.service)
+	assert.Empty(t, r2.service)
+	assert.Greater(t, r2.refreshed, 0)
+
+	counter.lk.Lock()
+	defer counter.lk.Unlock()
+	assert.Greater(t, counter.counts["*kratos.mockRegistry,register,success"], 0)
+	assert.Greater(t, counter.counts["*kratos.refreshRegistry,refresh,success"], 0)
+	assert.Equal(t, 1, counter.counts["*kratos.refreshRegistry,refresh,failure"])
+	assert.Equal(t, 1, counter.counts["*kratos.refreshRegistry,register,success"])
+}
+
+func TestApp_RegisterError(t *testing.T) {
+	srv := &testServer{}
+	// the empty id is rejected by the second registrar only.
+	app := New(ID(""), Server(srv), Registrar(&mockRegistrar{}, &mockRegistry{}))
+	assert.Error(t, app.Run())
+	assert.True(t, srv.stopped)
+	assert.Nil(t, app.instance)
+}
+
+func TestBackoff(t *testing.T) {
+	interval := 10 * time.Second
+	assert.Equal(t, time.Second, backoff(1, interval))
+	assert.Equal(t, 2*time.Second, backoff(2, interval))
+	assert.Equal(t, 8*time.Second, backoff(4, interval))
+	assert.Equal(t, interval, backoff(5, interval))
+	assert.Equal(t, 100*time.Millisecond, backoff(1, 100*time.Millisecond))
+}
@@ -0,0 +1,238 @@
+// Package cache implements a registry.Discovery decorator caching the last known
+// instances of the services, which are served while the backing discovery is
+// unavailable. The watchers of a service share a single backing watcher.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/internal/fileutil"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+)
+
+var _ registry.Discovery = (*Discovery)(nil)
+
+// Option is cache option.
+type Option func(*Discovery)
+
+// Snapshot with the file persisting the cached instances, which are loaded
+// when the cache is created, so that a cold start finds its peers during an outage.
+func Snapshot(path string) Option {
+	return func(d *Discovery) { d.snapshot = path }
+}
+
+// RetryInterval with the interval watching again a failed service, default is 1 second.
+func RetryInterval(t time.Duration) Option {
+	return func(d *Discovery) { d.retry = t }
+}
+
+// Logger with the logger of the discovery errors.
+func Logger(logger log.Logger) Option {
+	return func(d *Discovery) { d.log = log.NewHelper(logger) }
+}
+
+// Discovery is a registry.Discovery caching the instances of the backing one.
+type Discovery struct {
+	discovery registry.Discovery
+	snapshot  string
+	retry     time.Duration
+	log       *log.Helper
+
+	// mem holds the cached instances, and notifies the watchers.
+	mem *memory.Registry
+
+	lk       sync.Mutex
+	services map[string][]*registry.ServiceInstance
+	watches  map[string]*watch
+	wg       sync.WaitGroup
+}
+
+// watch is the backing watcher of a service, shared by its watchers.
+type watch struct {
+	refs   int
+	cancel context.CancelFunc
+}
+
+// New creates a cache of the discovery.
+func New(discovery registry.Discovery, opts ...Option) *Discovery {
+	d := &Discovery{
+		discovery: discovery,
+		retry:     time.Second,
+		log:       log.NewHelper(log.DefaultLogger),
+		mem:       memory.New(),
+		services:  make(map[string][]*registry.ServiceInstance),
+		watches:   make(map[string]*watch),
+	}
+	for _, o := range opts {
+		o(d)
+	}
+	if d.snapshot != "" {
+		if err := d.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			d.log.Errorf("failed to load discovery snapshot %s: %v", d.snapshot, err)
+		}
+	}
+	return d
+}
+
+// GetService returns the instances of the service from the backing discovery,
+// or the cached ones when it fails.
+func (d *Discovery) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	instances, err := d.discovery.GetService(ctx, name)
+	if err == nil {
+		d.update(name, instances)
+		return instances, nil
+	}
+	cached, _ := d.mem.GetService(context.Background(), name)
+	if len(cached) == 0 {
+		return nil, err
+	}
+	d.log.Warnf("serve the cached instances of %s: %v", name, err)
+	return cached, nil
+}
+
+// Watch creates a watcher of the service. The cached instances are returned
+// first, while the backing watcher is shared with the other watchers of the service.
+func (d *Discovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	w, err := d.mem.Watch(ctx, name)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	d.acquire(name)
+	go func() {
+		// the watcher is stopped by Stop or its context.
+		<-ctx.Done()
+		d.release(name)
+	}()
+	return &watcher{Watcher: w, cancel: cancel}, nil
+}
+
+// Close stops the backing watchers.
+func (d *Discovery) Close() error {
+	d.lk.Lock()
+	for name, w := range d.watches {
+		w.cancel()
+		delete(d.watches, name)
+	}
+	d.lk.Unlock()
+	d.wg.Wait()
+	return nil
+}
+
+func (d *Discovery) acquire(name string) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if w, ok := d.watches[name]; ok {
+		w.refs++
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.watches[name] = &watch{refs: 1, cancel: cancel}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.watch(ctx, name)
+	}()
+}
+
+func (d *Discovery) release(name string) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	w, ok := d.watches[name]
+	if !ok {
+		return
+	}
+	if w.refs--; w.refs == 0 {
+		w.cancel()
+		delete(d.watches, name)
+	}
+}
+
+// watch updates the cache with the backing watcher, which is created again after a failure.
+func (d *Discovery) watch(ctx context.Context, name string) {
+	for {
+		if err := d.watchOnce(ctx, name); err != nil && ctx.Err() == nil {
+			d.log.Errorf("failed to watch %s, serve the cached instances: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.retry):
+		}
+	}
+}
+
+func (d *Discovery) watchOnce(ctx context.Context, name string) error {
+	w, err := d.discovery.Watch(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+	for {
+		instances, err := w.Next()
+		if err != nil {
+			return err
+		}
+		d.update(name, instances)
+	}
+}
+
+func (d *Discovery) update(name string, instances []*registry.ServiceInstance) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if len(instances) == 0 {
+		delete(d.services, name)
+	} else {
+		d.services[name] = instances
+	}
+	d.mem.Set(name, instances...)
+	if d.snapshot != "" {
+		if err := d.save(); err != nil {
+			d.log.Errorf("failed to save discovery snapshot %s: %v", d.snapshot, err)
+		}
+	}
+}
+
+func (d *Discovery) load() error {
+	data, err := os.ReadFile(d.snapshot)
+	if err != nil {
+		return err
+	}
+	services := make(map[string][]*registry.ServiceInstance)
+	if err = json.Unmarshal(data, &services); err != nil {
+		return err
+	}
+	for name, instances := range services {
+		d.services[name] = instances
+		d.mem.Set(name, instances...)
+	}
+	return nil
+}
+
+// save writes the snapshot, it must be called with the lock held.
+func (d *Discovery) save() error {
+	data, err := json.Marshal(d.services)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(d.snapshot, data)
+}
+
+type watcher struct {
+	registry.Watcher
+	cancel context.CancelFunc
+}
+
+// Stop stops the watcher, and the backing watcher once the service has no more watchers.
+func (w *watcher) Stop() error {
+	w.cancel()
+	return w.Watcher.Stop()
+}
@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+)
+
+var errUnavailable = errors.New("discovery is unavailable")
+
+// flakyDiscovery is a discovery which fails during the outages.
+type flakyDiscovery struct {
+	*memory.Registry
+	lk       sync.Mutex
+	failing  bool
+	watches  int
+	watchers []registry.Watcher
+}
+
+func newFlakyDiscovery() *flakyDiscovery {
+	return &flakyDiscovery{Registry: memory.New()}
+}
+
+func (d *flakyDiscovery) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.failing {
+		return nil, errUnavailable
+	}
+	return d.Registry.GetService(ctx, name)
+}
+
+func (d *flakyDiscovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.failing {
+		return nil, errUnavailable
+	}
+	w, err := d.Registry.Watch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	d.watches++
+	d.watchers = append(d.watchers, w)
+	return w, nil
+}
+
+func (d *flakyDiscovery) setFailing(failing bool) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.failing = failing
+	if failing {
+		// the open watchers fail too.
+		for _, w := range d.watchers {
+			_ = w.Stop()
+		}
+		d.watchers = nil
+	}
+}
+
+func (d *flakyDiscovery) watchCount() int {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	return d.watches
+}
+
+func instance(id string) *registry.ServiceInstance {
+	return &registry.ServiceInstance{ID: id, Name: "helloworld", Endpoints: []string{"grpc://127.0.0.1:9000"}}
+}
+
+func next(t *testing.T, w registry.Watcher) []*registry.ServiceInstance {
+	t.Helper()
+	ch := make(chan []*registry.ServiceInstance, 1)
+	go func() {
+		services, err := w.Next()
+		assert.NoError(t, err)
+		ch <- services
+	}()
+	select {
+	case services := <-ch:
+		return services
+	case <-time.After(time.Second):
+		t.Fatal("the watcher is not notified")
+		return nil
+	}
+}
+
+func TestGetService(t *testing.T) {
+	ctx := context.Background()
+	d := newFlakyDiscovery()
+	assert.NoError(t, d.Register(ctx, instance("1")))
+	c := New(d)
+	defer c.Close()
+
+	services, err := c.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+
+	d.setFailing(true)
+	services, err = c.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Equal(t, []*registry.ServiceInstance{instance("1")}, services)
+	_, err = c.GetService(ctx, "unknown")
+	assert.Equal(t, errUnavailable, err)
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	d := newFlakyDiscovery()
+	assert.NoError(t, d.Register(ctx, instance("1")))
+	c := New(d, RetryInterval(10*time.Millisecond))
+	defer c.Close()
+
+	w1, err := c.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	w2, err := c.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Len(t, next(t, w1), 1)
+	assert.Len(t, next(t, w2), 1)
+	// the watchers share the backing watcher.
+	assert.Equal(t, 1, d.watchCount())
+
+	assert.NoError(t, d.Register(ctx, instance("2")))
+	assert.Len(t, next(t, w1), 2)
+	assert.Len(t, next(t, w2), 2)
+
+	// the cached instances are kept during the outage.
+	d.setFailing(true)
+	time.Sleep(50 * time.Millisecond)
+	services, err := c.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+
+	// the backing watcher is created again once the discovery is back.
+	assert.NoError(t, d.Deregister(ctx, instance("1")))
+	d.setFailing(false)
+	assert.Equal(t, []*registry.ServiceInstance{instance("2")}, next(t, w1))
+
+	assert.NoError(t, w1.Stop())
+	assert.NoError(t, w2.Stop())
+	assert.Eventually(t, func() bool {
+		c.lk.Lock()
+		defer c.lk.Unlock()
+		return len(c.watches) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWatchContext(t *testing.T) {
+	d := newFlakyDiscovery()
+	c := New(d)
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := c.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	cancel()
+	_, err = w.Next()
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Eventually(t, func() bool {
+		c.lk.Lock()
+		defer c.lk.Unlock()
+		return len(c.watches) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	d := newFlakyDiscovery()
+	assert.NoError(t, d.Register(ctx, instance("1")))
+	c := New(d, Snapshot(path))
+	_, err := c.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.NoError(t, c.Close())
+
+	// a cold start during an outage finds the instances of the snapshot.
+	d.setFailing(true)
+	c = New(d, Snapshot(path), RetryInterval(10*time.Millisecond))
+	defer c.Close()
+	services, err := c.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Equal(t, []*registry.ServiceInstance{instance("1")}, services)
+	w, err := c.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	defer w.Stop()
+	assert.Equal(t, []*registry.ServiceInstance{instance("1")}, next(t, w))
+}
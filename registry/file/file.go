@@ -0,0 +1,272 @@
+// Package file implements a static registry read from a YAML or JSON file,
+// which lists the service instances by service name, for example:
+//
+//   helloworld:
+//     - id: helloworld-1
+//       version: v1.0.0
+//       endpoints:
+//         - grpc://127.0.0.1:9000
+//
+// The file is reloaded when it changes, and the watchers are notified.
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/internal/fileutil"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+
+	// init encoding
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	_ "github.com/go-kratos/kratos/v2/encoding/yaml"
+)
+
+var (
+	_ registry.Registrar = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// ErrReadOnly is returned by Register and Deregister when the registry is not writable.
+var ErrReadOnly = errors.New("file: registry is read-only")
+
+// errEmpty is returned by reload when the file is empty, which is the state of
+// a file truncated before being written in place.
+var errEmpty = errors.New("file: registry file is empty")
+
+// Option is file registry option.
+type Option func(*Registry)
+
+// Writable with the registrations written back to the file.
+func Writable() Option {
+	return func(r *Registry) { r.writable = true }
+}
+
+// Logger with the logger of the reload errors.
+func Logger(logger log.Logger) Option {
+	return func(r *Registry) { r.log = log.NewHelper(logger) }
+}
+
+// Registry is a registry read from a file.
+type Registry struct {
+	path     string
+	codec    encoding.Codec
+	writable bool
+	log      *log.Helper
+
+	lk       sync.Mutex
+	services map[string][]*registry.ServiceInstance
+	mem      *memory.Registry
+
+	fw     *fsnotify.Watcher
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a registry read from the file at path, the format is chosen
+// by the file extension. The file is watched until Close is called.
+// The empty file lists no service when the registry is created, but it is
+// ignored afterwards, an empty list of services is written as {} instead.
+func New(path string, opts ...Option) (*Registry, error) {
+	codec := encoding.GetCodec(format(path))
+	if codec == nil {
+		return nil, fmt.Errorf("file: unsupported format of %s", path)
+	}
+	r := &Registry{
+		path:     filepath.Clean(path),
+		codec:    codec,
+		log:      log.NewHelper(log.DefaultLogger),
+		services: make(map[string][]*registry.ServiceInstance),
+		mem:      memory.New(),
+		done:     make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	if err := r.reload(); err != nil && !errors.Is(err, errEmpty) {
+		return nil, err
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// the directory is watched, so that the file replaced by a rename is followed.
+	if err = fw.Add(filepath.Dir(r.path)); err != nil {
+		_ = fw.Close()
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.fw = fw
+	r.cancel = cancel
+	go r.watch(ctx)
+	return r, nil
+}
+
+// GetService returns the instances of the service.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	return r.mem.GetService(ctx, name)
+}
+
+// Watch creates a watcher of the service, notified when the file changes.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	return r.mem.Watch(ctx, name)
+}
+
+// Register adds the instance to the file, replacing the one with the same id.
+func (r *Registry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	return r.update(ctx, service, func(instances []*registry.ServiceInstance) []*registry.ServiceInstance {
+		return append(remove(instances, service.ID), service)
+	})
+}
+
+// Deregister removes the instance from the file.
+func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	return r.update(ctx, service, func(instances []*registry.ServiceInstance) []*registry.ServiceInstance {
+		return remove(instances, service.ID)
+	})
+}
+
+// Close stops watching the file.
+func (r *Registry) Close() error {
+	r.cancel()
+	err := r.fw.Close()
+	<-r.done
+	return err
+}
+
+func (r *Registry) update(ctx context.Context, service *registry.ServiceInstance, fn func([]*registry.ServiceInstance) []*registry.ServiceInstance) error {
+	if !r.writable {
+		return ErrReadOnly
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if service == nil || service.Name == "" || service.ID == "" {
+		return memory.ErrInvalidInstance
+	}
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	services := make(map[string][]*registry.ServiceInstance, len(r.services)+1)
+	for name, instances := range r.services {
+		services[name] = instances
+	}
+	if instances := fn(services[service.Name]); len(instances) > 0 {
+		services[service.Name] = instances
+	} else {
+		delete(services, service.Name)
+	}
+	if err := r.write(services); err != nil {
+		return err
+	}
+	r.apply(services)
+	return nil
+}
+
+// write writes the services to the file.
+func (r *Registry) write(services map[string][]*registry.ServiceInstance) error {
+	data, err := r.codec.Marshal(services)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(r.path, data)
+}
+
+func (r *Registry) watch(ctx context.Context) {
+	defer close(r.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-r.fw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != r.path || event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				// the file may be truncated or partially written in place,
+				// the services are kept until the next event reloads it.
+				r.log.Errorf("failed to reload registry file %s: %v", r.path, err)
+			}
+		case err, ok := <-r.fw.Errors:
+			if !ok {
+				return
+			}
+			r.log.Errorf("failed to watch registry file %s: %v", r.path, err)
+		}
+	}
+}
+
+// reload reads the file, the services are kept when it fails or is empty.
+func (r *Registry) reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return errEmpty
+	}
+	services := make(map[string][]*registry.ServiceInstance)
+	if err = r.codec.Unmarshal(data, &services); err != nil {
+		return err
+	}
+	for name, instances := range services {
+		for _, ins := range instances {
+			if ins.Name == "" {
+				ins.Name = name
+			}
+		}
+	}
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.apply(services)
+	return nil
+}
+
+// apply replaces the services, only the changed ones are notified.
+// It must be called with the lock held.
+func (r *Registry) apply(services map[string][]*registry.ServiceInstance) {
+	for name, instances := range services {
+		if !reflect.DeepEqual(r.services[name], instances) {
+			r.mem.Set(name, instances...)
+		}
+	}
+	for name := range r.services {
+		if _, ok := services[name]; !ok {
+			r.mem.Set(name)
+		}
+	}
+	r.services = services
+}
+
+func remove(instances []*registry.ServiceInstance, id string) []*registry.ServiceInstance {
+	res := make([]*registry.ServiceInstance, 0, len(instances))
+	for _, ins := range instances {
+		if ins.ID != id {
+			res = append(res, ins)
+		}
+	}
+	return res
+}
+
+func format(path string) string {
+	switch ext := strings.TrimPrefix(filepath.Ext(path), "."); ext {
+	case "yml":
+		return "yaml"
+	default:
+		return ext
+	}
+}
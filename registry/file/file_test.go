@@ -0,0 +1,146 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+const testYAML = `
+helloworld:
+  - id: "1"
+    version: v1.0.0
+    metadata:
+      zone: a
+    endpoints:
+      - grpc://127.0.0.1:9000
+  - id: "2"
+    endpoints:
+      - grpc://127.0.0.1:9001
+`
+
+func next(t *testing.T, w registry.Watcher) []*registry.ServiceInstance {
+	t.Helper()
+	ch := make(chan []*registry.ServiceInstance, 1)
+	go func() {
+		services, err := w.Next()
+		assert.NoError(t, err)
+		ch <- services
+	}()
+	select {
+	case services := <-ch:
+		return services
+	case <-time.After(2 * time.Second):
+		t.Fatal("the watcher is not notified")
+		return nil
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "registry.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(testYAML), 0o644))
+	r, err := New(path)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	services, err := r.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Equal(t, []*registry.ServiceInstance{
+		{
+			ID:        "1",
+			Name:      "helloworld",
+			Version:   "v1.0.0",
+			Metadata:  map[string]string{"zone": "a"},
+			Endpoints: []string{"grpc://127.0.0.1:9000"},
+		},
+		{
+			ID:        "2",
+			Name:      "helloworld",
+			Endpoints: []string{"grpc://127.0.0.1:9001"},
+		},
+	}, services)
+	assert.Equal(t, ErrReadOnly, r.Register(ctx, services[0]))
+
+	w, err := r.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	defer w.Stop()
+	assert.Len(t, next(t, w), 2)
+
+	// the invalid file is ignored.
+	assert.NoError(t, os.WriteFile(path, []byte("helloworld: ["), 0o644))
+	time.Sleep(100 * time.Millisecond)
+	services, err = r.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+
+	// the file truncated before being written in place is ignored.
+	assert.NoError(t, os.Truncate(path, 0))
+	time.Sleep(100 * time.Millisecond)
+	services, err = r.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+
+	// the file replaced by a rename is reloaded.
+	tmp := filepath.Join(filepath.Dir(path), "tmp.yaml")
+	assert.NoError(t, os.WriteFile(tmp, []byte("helloworld:\n  - id: \"3\"\n"), 0o644))
+	assert.NoError(t, os.Rename(tmp, path))
+	services = next(t, w)
+	assert.Len(t, services, 1)
+	assert.Equal(t, "3", services[0].ID)
+}
+
+func TestWritable(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "registry.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{}`), 0o644))
+	r, err := New(path, Writable())
+	assert.NoError(t, err)
+	defer r.Close()
+
+	ins := &registry.ServiceInstance{ID: "1", Name: "helloworld", Endpoints: []string{"http://127.0.0.1:8000"}}
+	assert.NoError(t, r.Register(ctx, ins))
+	services, err := r.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Equal(t, []*registry.ServiceInstance{ins}, services)
+
+	// the registration is written back to the file.
+	reader, err := New(path)
+	assert.NoError(t, err)
+	defer reader.Close()
+	services, err = reader.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, ins.Endpoints, services[0].Endpoints)
+
+	w, err := reader.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	defer w.Stop()
+	assert.Len(t, next(t, w), 1)
+	assert.NoError(t, r.Deregister(ctx, ins))
+	assert.Empty(t, next(t, w))
+}
+
+func TestEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.yaml")
+	assert.NoError(t, os.WriteFile(path, nil, 0o644))
+	r, err := New(path)
+	assert.NoError(t, err)
+	defer r.Close()
+	services, err := r.GetService(context.Background(), "helloworld")
+	assert.NoError(t, err)
+	assert.Empty(t, services)
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "yaml", format("registry.yml"))
+	assert.Equal(t, "json", format("/etc/registry.json"))
+	_, err := New("registry.txt")
+	assert.Error(t, err)
+}
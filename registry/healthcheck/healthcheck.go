@@ -0,0 +1,326 @@
+// Package healthcheck implements a registry.Discovery decorator probing the
+// endpoints of the watched instances, so that the unhealthy instances are
+// removed until they pass consecutive probes again. The endpoints are probed
+// by their scheme: gRPC with grpc.health.v1, and the others with a TCP
+// connection unless a prober is given for the scheme, for example the HTTP
+// endpoints of kratos servers exposing their health:
+//
+//   healthcheck.New(r, healthcheck.Probe("http", healthcheck.HTTP(health.LivePath)))
+package healthcheck
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+var _ registry.Discovery = (*Discovery)(nil)
+
+// Option is health check option.
+type Option func(*Discovery)
+
+// Interval with the interval of the probes, default is 5 seconds.
+func Interval(t time.Duration) Option {
+	return func(d *Discovery) { d.interval = t }
+}
+
+// Timeout with the timeout of a probe, default is 1 second.
+func Timeout(t time.Duration) Option {
+	return func(d *Discovery) { d.timeout = t }
+}
+
+// HealthyThreshold with the consecutive successful probes bringing back
+// an unhealthy endpoint, default is 2.
+func HealthyThreshold(n int) Option {
+	return func(d *Discovery) { d.healthy = n }
+}
+
+// UnhealthyThreshold with the consecutive failed probes removing
+// a healthy endpoint, default is 1.
+func UnhealthyThreshold(n int) Option {
+	return func(d *Discovery) { d.unhealthy = n }
+}
+
+// Probe with the prober of the endpoints with the scheme.
+func Probe(scheme string, p Prober) Option {
+	return func(d *Discovery) { d.probers[scheme] = p }
+}
+
+// Logger with the logger of the health changes.
+func Logger(logger log.Logger) Option {
+	return func(d *Discovery) { d.log = log.NewHelper(logger) }
+}
+
+// target is a probed endpoint.
+type target struct {
+	endpoint  *url.URL
+	healthy   bool
+	successes int
+	failures  int
+	// refs is the number of the watchers listing the endpoint.
+	refs int
+}
+
+// Discovery is a registry.Discovery removing the unhealthy instances.
+type Discovery struct {
+	discovery registry.Discovery
+	interval  time.Duration
+	timeout   time.Duration
+	healthy   int
+	unhealthy int
+	probers   map[string]Prober
+	grpc      *grpcProber
+	log       *log.Helper
+
+	lk       sync.RWMutex
+	targets  map[string]*target
+	watchers map[*watcher]struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a health checking discovery, the endpoints are probed until Close is called.
+func New(discovery registry.Discovery, opts ...Option) *Discovery {
+	d := &Discovery{
+		discovery: discovery,
+		interval:  5 * time.Second,
+		timeout:   time.Second,
+		healthy:   2,
+		unhealthy: 1,
+		probers:   make(map[string]Prober),
+		grpc:      newGRPCProber(),
+		log:       log.NewHelper(log.DefaultLogger),
+		targets:   make(map[string]*target),
+		watchers:  make(map[*watcher]struct{}),
+		done:      make(chan struct{}),
+	}
+	d.probers["grpc"] = d.grpc.probe
+	for _, o := range opts {
+		o(d)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	go d.run(ctx)
+	return d
+}
+
+// GetService returns the instances of the service, without the ones known unhealthy.
+func (d *Discovery) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	instances, err := d.discovery.GetService(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return d.filter(instances), nil
+}
+
+// Watch creates a watcher of the service, its instances are probed until it is stopped.
+// Next returns the healthy instances when the instances or their health change.
+func (d *Discovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	w, err := d.discovery.Watch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	hw := &watcher{
+		d:      d,
+		w:      w,
+		event:  make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	d.lk.Lock()
+	d.watchers[hw] = struct{}{}
+	d.lk.Unlock()
+	go hw.watch()
+	return hw, nil
+}
+
+// Filter returns a selector filter removing the nodes whose address is an unhealthy endpoint.
+func (d *Discovery) Filter() selector.Filter {
+	return func(_ context.Context, nodes []selector.Node) []selector.Node {
+		d.lk.RLock()
+		unhealthy := make(map[string]struct{})
+		for _, t := range d.targets {
+			if !t.healthy {
+				unhealthy[t.endpoint.Host] = struct{}{}
+			}
+		}
+		d.lk.RUnlock()
+		if len(unhealthy) == 0 {
+			return nodes
+		}
+		newNodes := make([]selector.Node, 0, len(nodes))
+		for _, n := range nodes {
+			if _, ok := unhealthy[n.Address()]; !ok {
+				newNodes = append(newNodes, n)
+			}
+		}
+		return newNodes
+	}
+}
+
+// Close stops probing the endpoints.
+func (d *Discovery) Close() error {
+	d.cancel()
+	<-d.done
+	d.grpc.close()
+	return nil
+}
+
+// filter returns the instances whose endpoints are not known unhealthy.
+func (d *Discovery) filter(instances []*registry.ServiceInstance) []*registry.ServiceInstance {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+	res := make([]*registry.ServiceInstance, 0, len(instances))
+	for _, ins := range instances {
+		healthy := true
+		for _, e := range ins.Endpoints {
+			if t, ok := d.targets[e]; ok && !t.healthy {
+				healthy = false
+				break
+			}
+		}
+		if healthy {
+			res = append(res, ins)
+		}
+	}
+	return res
+}
+
+// track replaces the endpoints probed for a watcher, the new endpoints are healthy until probed.
+func (d *Discovery) track(old, instances []*registry.ServiceInstance) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	for _, ins := range instances {
+		for _, e := range ins.Endpoints {
+			if t, ok := d.targets[e]; ok {
+				t.refs++
+				continue
+			}
+			u, err := url.Parse(e)
+			if err != nil {
+				d.log.Errorf("failed to parse endpoint %s: %v", e, err)
+				continue
+			}
+			d.targets[e] = &target{endpoint: u, healthy: true, refs: 1}
+		}
+	}
+	d.untrack(old)
+}
+
+// untrack releases the endpoints of the instances. It must be called with the lock held.
+func (d *Discovery) untrack(instances []*registry.ServiceInstance) {
+	for _, ins := range instances {
+		for _, e := range ins.Endpoints {
+			if t, ok := d.targets[e]; ok {
+				if t.refs--; t.refs == 0 {
+					delete(d.targets, e)
+					d.grpc.release(t.endpoint)
+				}
+			}
+		}
+	}
+}
+
+func (d *Discovery) run(ctx context.Context) {
+	defer close(d.done)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.probe(ctx)
+		}
+	}
+}
+
+// probe probes the endpoints concurrently, and notifies the watchers when the health changes.
+func (d *Discovery) probe(ctx context.Context) {
+	d.lk.RLock()
+	targets := make(map[string]*url.URL, len(d.targets))
+	for e, t := range d.targets {
+		targets[e] = t.endpoint
+	}
+	d.lk.RUnlock()
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]error, len(targets))
+	)
+	for e, u := range targets {
+		wg.Add(1)
+		go func(e string, u *url.URL) {
+			defer wg.Done()
+			p, ok := d.probers[u.Scheme]
+			if !ok {
+				p = TCP()
+			}
+			ctx, cancel := context.WithTimeout(ctx, d.timeout)
+			defer cancel()
+			err := p(ctx, u)
+			mu.Lock()
+			results[e] = err
+			mu.Unlock()
+		}(e, u)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+	if d.apply(results) {
+		d.notify()
+	}
+}
+
+// apply applies the results of the probes, and returns whether the health of an endpoint changed.
+func (d *Discovery) apply(results map[string]error) (changed bool) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	for e, err := range results {
+		t, ok := d.targets[e]
+		if !ok {
+			continue
+		}
+		if err != nil {
+			t.successes = 0
+			t.failures++
+			if t.healthy && t.failures >= d.unhealthy {
+				t.healthy = false
+				changed = true
+				d.log.Warnf("endpoint %s is unhealthy: %v", e, err)
+			}
+			continue
+		}
+		t.failures = 0
+		t.successes++
+		if !t.healthy && t.successes >= d.healthy {
+			t.healthy = true
+			changed = true
+			d.log.Infof("endpoint %s is healthy", e)
+		}
+	}
+	return changed
+}
+
+func (d *Discovery) notify() {
+	d.lk.RLock()
+	defer d.lk.RUnlock()
+	for w := range d.watchers {
+		w.notify()
+	}
+}
+
+func (d *Discovery) removeWatcher(w *watcher, instances []*registry.ServiceInstance) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	delete(d.watchers, w)
+	d.untrack(instances)
+}
@@ -0,0 +1,244 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/go-kratos/kratos/v2/health"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
+	"github.com/go-kratos/kratos/v2/selector"
+)
+
+// healthServer is a local HTTP server whose liveness is toggled.
+type healthServer struct {
+	*httptest.Server
+	down int32
+}
+
+func newHealthServer() *healthServer {
+	s := &healthServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != health.LivePath || atomic.LoadInt32(&s.down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	return s
+}
+
+func (s *healthServer) setDown(down bool) {
+	if down {
+		atomic.StoreInt32(&s.down, 1)
+	} else {
+		atomic.StoreInt32(&s.down, 0)
+	}
+}
+
+func next(t *testing.T, w registry.Watcher) []*registry.ServiceInstance {
+	t.Helper()
+	ch := make(chan []*registry.ServiceInstance, 1)
+	go func() {
+		services, err := w.Next()
+		assert.NoError(t, err)
+		ch <- services
+	}()
+	select {
+	case services := <-ch:
+		return services
+	case <-time.After(2 * time.Second):
+		t.Fatal("the watcher is not notified")
+		return nil
+	}
+}
+
+func TestTCP(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	u := &url.URL{Scheme: "tcp", Host: lis.Addr().String()}
+	assert.NoError(t, TCP()(context.Background(), u))
+	lis.Close()
+	assert.Error(t, TCP()(context.Background(), u))
+}
+
+func TestHTTP(t *testing.T) {
+	srv := newHealthServer()
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	assert.NoError(t, HTTP(health.LivePath)(context.Background(), u))
+	assert.Error(t, HTTP("/unknown")(context.Background(), u))
+	srv.setDown(true)
+	assert.Error(t, HTTP(health.LivePath)(context.Background(), u))
+}
+
+func TestGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	hs := grpchealth.NewServer()
+	srv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, hs)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	u := &url.URL{Scheme: "grpc", Host: lis.Addr().String()}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, GRPC()(ctx, u))
+
+	// the connection is reused by the probes of the endpoint.
+	p := newGRPCProber()
+	defer p.close()
+	assert.NoError(t, p.probe(ctx, u))
+	conn := p.conns[u.String()]
+	assert.NotNil(t, conn)
+	assert.NoError(t, p.probe(ctx, u))
+	assert.Len(t, p.conns, 1)
+	assert.Equal(t, conn, p.conns[u.String()])
+
+	hs.Shutdown()
+	assert.Error(t, GRPC()(ctx, u))
+	assert.Error(t, p.probe(ctx, u))
+	assert.Len(t, p.conns, 1)
+
+	// the failed connection is closed, the next probe dials again.
+	srv.Stop()
+	assert.Error(t, p.probe(ctx, u))
+	assert.Empty(t, p.conns)
+}
+
+func TestDiscovery(t *testing.T) {
+	ctx := context.Background()
+	srv1, srv2 := newHealthServer(), newHealthServer()
+	defer srv1.Close()
+	defer srv2.Close()
+	r := memory.New()
+	ins1 := &registry.ServiceInstance{ID: "1", Name: "helloworld", Endpoints: []string{srv1.URL}}
+	ins2 := &registry.ServiceInstance{ID: "2", Name: "helloworld", Endpoints: []string{srv2.URL}}
+	assert.NoError(t, r.Register(ctx, ins1))
+	assert.NoError(t, r.Register(ctx, ins2))
+
+	d := New(r, Interval(20*time.Millisecond), HealthyThreshold(2), Probe("http", HTTP(health.LivePath)))
+	defer d.Close()
+	w, err := d.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	defer w.Stop()
+	assert.Len(t, next(t, w), 2)
+
+	// the unhealthy instance is removed.
+	srv1.setDown(true)
+	assert.Equal(t, []*registry.ServiceInstance{ins2}, next(t, w))
+	services, err := d.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Equal(t, []*registry.ServiceInstance{ins2}, services)
+	u1, _ := url.Parse(srv1.URL)
+	u2, _ := url.Parse(srv2.URL)
+	nodes := d.Filter()(ctx, []selector.Node{
+		selector.NewNode(u1.Host, ins1),
+		selector.NewNode(u2.Host, ins2),
+	})
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, u2.Host, nodes[0].Address())
+
+	// the instance is back after consecutive successful probes.
+	srv1.setDown(false)
+	assert.Len(t, next(t, w), 2)
+
+	// the deregistered instance is no longer probed.
+	assert.NoError(t, r.Deregister(ctx, ins1))
+	assert.Equal(t, []*registry.ServiceInstance{ins2}, next(t, w))
+	d.lk.RLock()
+	assert.Len(t, d.targets, 1)
+	d.lk.RUnlock()
+}
+
+func TestWatcherStop(t *testing.T) {
+	d := New(memory.New())
+	defer d.Close()
+	w, err := d.Watch(context.Background(), "helloworld")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Stop())
+	_, err = w.Next()
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool {
+		d.lk.RLock()
+		defer d.lk.RUnlock()
+		return len(d.watchers) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDefaultProbers(t *testing.T) {
+	ctx := context.Background()
+	// the HTTP endpoints are probed with a TCP connection by default.
+	srv := newHealthServer()
+	defer srv.Close()
+	srv.setDown(true)
+	r := memory.New()
+	ins := &registry.ServiceInstance{ID: "1", Name: "helloworld", Endpoints: []string{srv.URL}}
+	assert.NoError(t, r.Register(ctx, ins))
+
+	d := New(r, Interval(20*time.Millisecond))
+	defer d.Close()
+	w, err := d.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	defer w.Stop()
+	assert.Len(t, next(t, w), 1)
+	time.Sleep(100 * time.Millisecond)
+	services, err := d.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+}
+
+// errDiscovery is a discovery whose watchers keep failing.
+type errDiscovery struct {
+	registry.Discovery
+	calls int32
+}
+
+func (d *errDiscovery) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	return &errWatcher{d: d}, nil
+}
+
+type errWatcher struct {
+	d *errDiscovery
+}
+
+func (w *errWatcher) Next() ([]*registry.ServiceInstance, error) {
+	atomic.AddInt32(&w.d.calls, 1)
+	return nil, errors.New("discovery is unavailable")
+}
+
+func (w *errWatcher) Stop() error {
+	return nil
+}
+
+func TestWatcherErrors(t *testing.T) {
+	ed := &errDiscovery{}
+	d := New(ed, Interval(time.Second))
+	defer d.Close()
+	w, err := d.Watch(context.Background(), "helloworld")
+	assert.NoError(t, err)
+	defer w.Stop()
+
+	time.Sleep(500 * time.Millisecond)
+	// the backing watcher is retried with backoff: 100ms, 200ms, 400ms.
+	assert.LessOrEqual(t, atomic.LoadInt32(&ed.calls), int32(4))
+	_, err = w.Next()
+	assert.EqualError(t, err, "discovery is unavailable")
+	hw := w.(*watcher)
+	hw.lk.Lock()
+	assert.Nil(t, hw.err)
+	hw.lk.Unlock()
+}
@@ -0,0 +1,134 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/go-kratos/kratos/v2/internal/endpoint"
+)
+
+// Prober probes an instance endpoint, an error reports it unhealthy.
+type Prober func(ctx context.Context, endpoint *url.URL) error
+
+// TCP returns a prober connecting to the endpoint.
+func TCP() Prober {
+	return func(ctx context.Context, u *url.URL) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTP returns a prober requesting the path of the endpoint, the endpoint
+// is healthy when the response status is 2xx.
+func HTTP(path string) Prober {
+	return func(ctx context.Context, u *url.URL) error {
+		scheme := "http"
+		if u.Scheme == "https" || endpoint.IsSecure(u) {
+			scheme = "https"
+		}
+		target := &url.URL{Scheme: scheme, Host: u.Host, Path: path}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("healthcheck: %s responds %s", target, resp.Status)
+		}
+		return nil
+	}
+}
+
+// GRPC returns a prober checking the endpoint with the grpc.health.v1 service.
+// The connection of an endpoint is reused by the probes until one fails.
+func GRPC() Prober {
+	return newGRPCProber().probe
+}
+
+// grpcProber keeps a connection per probed endpoint.
+type grpcProber struct {
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func newGRPCProber() *grpcProber {
+	return &grpcProber{conns: make(map[string]*grpc.ClientConn)}
+}
+
+func (p *grpcProber) probe(ctx context.Context, u *url.URL) error {
+	conn, err := p.conn(ctx, u)
+	if err != nil {
+		return err
+	}
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		// the connection may be waiting to reconnect, the next probe dials again.
+		p.release(u)
+		return err
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("healthcheck: %s is %s", u.Host, resp.Status)
+	}
+	return nil
+}
+
+// conn returns the connection of the endpoint, dialed on the first probe.
+func (p *grpcProber) conn(ctx context.Context, u *url.URL) (*grpc.ClientConn, error) {
+	key := u.String()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conn, ok := p.conns[key]; ok {
+		return conn, nil
+	}
+	creds := insecure.NewCredentials()
+	if endpoint.IsSecure(u) {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.DialContext(ctx, u.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	p.conns[key] = conn
+	return conn, nil
+}
+
+// release closes the connection of the endpoint.
+func (p *grpcProber) release(u *url.URL) {
+	key := u.String()
+	p.mu.Lock()
+	conn, ok := p.conns[key]
+	delete(p.conns, key)
+	p.mu.Unlock()
+	if ok {
+		_ = conn.Close()
+	}
+}
+
+// close closes all the connections.
+func (p *grpcProber) close() {
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = make(map[string]*grpc.ClientConn)
+	p.mu.Unlock()
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,122 @@
+package healthcheck
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+// minRetryDelay is the first delay before calling the backing watcher again after an error.
+const minRetryDelay = 100 * time.Millisecond
+
+type watcher struct {
+	d *Discovery
+	w registry.Watcher
+	// event holds a pending change of the instances or their health.
+	event chan struct{}
+
+	lk        sync.Mutex
+	instances []*registry.ServiceInstance
+	received  bool
+	// err is the latest error of the backing watcher not returned yet.
+	err error
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (w *watcher) notify() {
+	select {
+	case w.event <- struct{}{}:
+	default:
+	}
+}
+
+// watch receives the instances of the backing watcher until the watcher is stopped.
+func (w *watcher) watch() {
+	defer func() {
+		w.lk.Lock()
+		defer w.lk.Unlock()
+		w.d.removeWatcher(w, w.instances)
+		w.instances = nil
+	}()
+	var failures int
+	for {
+		instances, err := w.w.Next()
+		if w.ctx.Err() != nil {
+			return
+		}
+		w.lk.Lock()
+		if err != nil {
+			w.err = err
+		} else {
+			w.d.track(w.instances, instances)
+			w.instances = instances
+			w.received = true
+		}
+		w.lk.Unlock()
+		w.notify()
+		if err == nil {
+			failures = 0
+			continue
+		}
+		failures++
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-time.After(w.retryDelay(failures)):
+		}
+	}
+}
+
+// retryDelay returns the delay after the consecutive errors of the backing watcher,
+// doubled on each error and bounded by the probe interval.
+func (w *watcher) retryDelay(failures int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < failures && delay < w.d.interval; i++ {
+		delay *= 2
+	}
+	if delay > w.d.interval {
+		delay = w.d.interval
+	}
+	return delay
+}
+
+// Next returns the healthy instances once the instances or their health change,
+// or the latest error of the backing watcher.
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case <-w.event:
+		}
+		w.lk.Lock()
+		if err := w.err; err != nil {
+			w.err = nil
+			if w.received {
+				w.notify()
+			}
+			w.lk.Unlock()
+			return nil, err
+		}
+		if !w.received {
+			// the health changed before the first instances are received.
+			w.lk.Unlock()
+			continue
+		}
+		instances := w.instances
+		w.lk.Unlock()
+		return w.d.filter(instances), nil
+	}
+}
+
+// Stop stops the watcher and the backing watcher.
+func (w *watcher) Stop() error {
+	w.cancel()
+	return w.w.Stop()
+}
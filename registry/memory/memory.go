@@ -0,0 +1,153 @@
+// Package memory implements an in-process registry, for the tests and the
+// deployments running all the services in a single binary.
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var (
+	_ registry.Registrar = (*Registry)(nil)
+	_ registry.Discovery = (*Registry)(nil)
+)
+
+// ErrInvalidInstance is returned when a service instance has no name or id.
+var ErrInvalidInstance = errors.New("memory: invalid service instance")
+
+// Registry is an in-memory registry, safe for concurrent use.
+type Registry struct {
+	lk       sync.RWMutex
+	services map[string][]*registry.ServiceInstance
+	watchers map[string]map[*watcher]struct{}
+}
+
+// New creates an empty in-memory registry.
+func New() *Registry {
+	return &Registry{
+		services: make(map[string][]*registry.ServiceInstance),
+		watchers: make(map[string]map[*watcher]struct{}),
+	}
+}
+
+// Register registers the instance, replacing the one with the same id.
+func (r *Registry) Register(ctx context.Context, service *registry.ServiceInstance) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if service == nil || service.Name == "" || service.ID == "" {
+		return ErrInvalidInstance
+	}
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	instances := r.services[service.Name]
+	for i, ins := range instances {
+		if ins.ID == service.ID {
+			instances = append(instances[:i:i], instances[i+1:]...)
+			break
+		}
+	}
+	r.services[service.Name] = append(instances, service)
+	r.notify(service.Name)
+	return nil
+}
+
+// Deregister deregisters the instance, it is a no-op when the instance is not registered.
+func (r *Registry) Deregister(ctx context.Context, service *registry.ServiceInstance) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if service == nil || service.Name == "" || service.ID == "" {
+		return ErrInvalidInstance
+	}
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	instances := r.services[service.Name]
+	for i, ins := range instances {
+		if ins.ID == service.ID {
+			r.services[service.Name] = append(instances[:i:i], instances[i+1:]...)
+			r.notify(service.Name)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Set replaces the instances of the service at once, which simulates the churn of the instances.
+func (r *Registry) Set(name string, instances ...*registry.ServiceInstance) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if len(instances) == 0 {
+		delete(r.services, name)
+	} else {
+		r.services[name] = append([]*registry.ServiceInstance(nil), instances...)
+	}
+	r.notify(name)
+}
+
+// GetService returns the instances of the service.
+func (r *Registry) GetService(ctx context.Context, name string) ([]*registry.ServiceInstance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.instances(name), nil
+}
+
+// Watch creates a watcher of the service, which is stopped by Stop or when ctx is done.
+func (r *Registry) Watch(ctx context.Context, name string) (registry.Watcher, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watcher{
+		r:      r,
+		name:   name,
+		event:  make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if r.watchers[name] == nil {
+		r.watchers[name] = make(map[*watcher]struct{})
+	}
+	r.watchers[name][w] = struct{}{}
+	// the first Next returns the instances, if any.
+	if len(r.services[name]) > 0 {
+		w.notify()
+	}
+	return w, nil
+}
+
+func (r *Registry) instances(name string) []*registry.ServiceInstance {
+	r.lk.RLock()
+	defer r.lk.RUnlock()
+	return append([]*registry.ServiceInstance{}, r.services[name]...)
+}
+
+// notify notifies the watchers of the service, removing the stopped ones.
+// It must be called with the lock held.
+func (r *Registry) notify(name string) {
+	for w := range r.watchers[name] {
+		if w.ctx.Err() != nil {
+			delete(r.watchers[name], w)
+			continue
+		}
+		w.notify()
+	}
+	if len(r.watchers[name]) == 0 {
+		delete(r.watchers, name)
+	}
+}
+
+func (r *Registry) removeWatcher(w *watcher) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	delete(r.watchers[w.name], w)
+	if len(r.watchers[w.name]) == 0 {
+		delete(r.watchers, w.name)
+	}
+}
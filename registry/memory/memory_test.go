@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+func instance(id string) *registry.ServiceInstance {
+	return &registry.ServiceInstance{
+		ID:        id,
+		Name:      "helloworld",
+		Endpoints: []string{"http://127.0.0.1:8000"},
+	}
+}
+
+func next(t *testing.T, w registry.Watcher) []*registry.ServiceInstance {
+	t.Helper()
+	type result struct {
+		instances []*registry.ServiceInstance
+		err       error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		instances, err := w.Next()
+		ch <- result{instances, err}
+	}()
+	select {
+	case res := <-ch:
+		assert.NoError(t, res.err)
+		return res.instances
+	case <-time.After(time.Second):
+		t.Fatal("Next is blocked")
+		return nil
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	r := New()
+	assert.Equal(t, ErrInvalidInstance, r.Register(ctx, &registry.ServiceInstance{Name: "helloworld"}))
+	assert.NoError(t, r.Register(ctx, instance("1")))
+	assert.NoError(t, r.Register(ctx, instance("2")))
+	assert.NoError(t, r.Register(ctx, instance("1")))
+	services, err := r.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+
+	assert.NoError(t, r.Deregister(ctx, instance("1")))
+	assert.NoError(t, r.Deregister(ctx, instance("1")))
+	services, err = r.GetService(ctx, "helloworld")
+	assert.NoError(t, err)
+	assert.Equal(t, []*registry.ServiceInstance{instance("2")}, services)
+
+	services, err = r.GetService(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.Empty(t, services)
+}
+
+func TestWatcher(t *testing.T) {
+	ctx := context.Background()
+	r := New()
+	assert.NoError(t, r.Register(ctx, instance("1")))
+
+	w1, err := r.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	w2, err := r.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	// the first Next returns the registered instances.
+	assert.Len(t, next(t, w1), 1)
+	assert.Len(t, next(t, w2), 1)
+
+	done := make(chan []*registry.ServiceInstance)
+	go func() {
+		services, _ := w1.Next()
+		done <- services
+	}()
+	select {
+	case <-done:
+		t.Fatal("Next returns without changes")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.NoError(t, r.Register(ctx, instance("2")))
+	assert.Len(t, <-done, 2)
+	assert.Len(t, next(t, w2), 2)
+
+	// the changes are coalesced.
+	r.Set("helloworld", instance("3"), instance("4"), instance("5"))
+	assert.NoError(t, r.Deregister(ctx, instance("5")))
+	assert.Equal(t, []*registry.ServiceInstance{instance("3"), instance("4")}, next(t, w1))
+
+	r.Set("helloworld")
+	assert.Empty(t, next(t, w2))
+}
+
+func TestWatcherStop(t *testing.T) {
+	r := New()
+	w, err := r.Watch(context.Background(), "helloworld")
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = w.Stop()
+	}()
+	_, err = w.Next()
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Eventually(t, func() bool {
+		r.lk.RLock()
+		defer r.lk.RUnlock()
+		return len(r.watchers) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWatcherContext(t *testing.T) {
+	r := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := r.Watch(ctx, "helloworld")
+	assert.NoError(t, err)
+	cancel()
+	_, err = w.Next()
+	assert.True(t, errors.Is(err, context.Canceled))
+	// the canceled watcher is removed once the service changes.
+	r.Set("helloworld", instance("1"))
+	assert.Empty(t, r.watchers)
+
+	_, err = r.Watch(ctx, "helloworld")
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/registry"
+)
+
+var _ registry.Watcher = (*watcher)(nil)
+
+type watcher struct {
+	r    *Registry
+	name string
+	// event holds a pending change, the changes are coalesced
+	// until Next returns the latest instances.
+	event chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (w *watcher) notify() {
+	select {
+	case w.event <- struct{}{}:
+	default:
+	}
+}
+
+// Next blocks until the instances change, and returns them.
+func (w *watcher) Next() ([]*registry.ServiceInstance, error) {
+	if err := w.ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case <-w.event:
+		return w.r.instances(w.name), nil
+	}
+}
+
+// Stop stops the watcher, the blocked Next returns context.Canceled.
+func (w *watcher) Stop() error {
+	w.cancel()
+	w.r.removeWatcher(w)
+	return nil
+}
@@ -10,6 +10,14 @@ type Registrar interface {
 	Deregister(ctx context.Context, service *ServiceInstance) error
 }
 
+// Refresher is implemented by the registrars able to verify and refresh a
+// registration, for example by renewing its lease. An error reports the
+// registration is lost, so that it is registered again.
+type Refresher interface {
+	// Refresh the registration.
+	Refresh(ctx context.Context, service *ServiceInstance) error
+}
+
 // Discovery is service discovery.
 type Discovery interface {
 	// GetService return the service instances in memory according to the service name.
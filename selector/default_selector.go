@@ -64,6 +64,10 @@ func (d *Default) Apply(nodes []Node) {
 	}
 	// TODO: Do not delete unchanged nodes
 	d.nodes.Store(weightedNodes)
+	// the balancers keeping a state of the nodes, such as a hash ring, are applied too.
+	if r, ok := d.Balancer.(Rebalancer); ok {
+		r.Apply(nodes)
+	}
 }
 
 // DefaultBuilder is de
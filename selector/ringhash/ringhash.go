@@ -0,0 +1,270 @@
+package ringhash
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/node/direct"
+)
+
+const (
+	// Name is ringhash balancer name
+	Name = "ringhash"
+
+	// defaultReplicas is the default number of the virtual nodes of a node on the ring.
+	defaultReplicas = 160
+)
+
+var (
+	_ selector.Balancer   = &Balancer{}
+	_ selector.Rebalancer = &Balancer{}
+)
+
+// WithFilter with select filters
+func WithFilter(filters ...selector.Filter) Option {
+	return func(o *options) {
+		o.filters = filters
+	}
+}
+
+// WithMetadataKey with the client metadata key whose value is the hash key,
+// when the context has no key set by NewContext.
+func WithMetadataKey(key string) Option {
+	return func(o *options) {
+		o.metadataKey = key
+	}
+}
+
+// WithReplicas with the number of the virtual nodes of a node on the ring,
+// default is 160 and at least 1.
+func WithReplicas(n int) Option {
+	return func(o *options) {
+		if n < 1 {
+			n = 1
+		}
+		o.replicas = n
+	}
+}
+
+// Option is ringhash builder option.
+type Option func(o *options)
+
+// options is ringhash builder options
+type options struct {
+	filters     []selector.Filter
+	metadataKey string
+	replicas    int
+}
+
+type keyContext struct{}
+
+// NewContext returns a new context carrying the hash key of the request.
+func NewContext(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContext{}, key)
+}
+
+// FromContext returns the hash key of the request stored in ctx, if any.
+func FromContext(ctx context.Context) (key string, ok bool) {
+	key, ok = ctx.Value(keyContext{}).(string)
+	return
+}
+
+// ring is an immutable hash ring.
+type ring struct {
+	hashes []uint64
+	addrs  []string
+	// nodes holds the hashes of the virtual nodes by node address.
+	nodes map[string][]uint64
+}
+
+// Balancer is a consistent hashing balancer, the requests with the same
+// hash key are picked the same node as long as it is available.
+// The requests without a hash key are picked a random node.
+type Balancer struct {
+	metadataKey string
+	replicas    int
+
+	mu   sync.Mutex
+	ring atomic.Value
+}
+
+// New a ringhash selector.
+func New(opts ...Option) selector.Selector {
+	return NewBuilder(opts...).Build()
+}
+
+// Apply updates the ring with the nodes, only the virtual nodes of the
+// added and removed nodes are computed.
+func (b *Balancer) Apply(nodes []selector.Node) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	old, _ := b.ring.Load().(*ring)
+	b.ring.Store(b.build(old, nodes))
+}
+
+// applied returns the ring, which is built of the nodes when none has been applied yet.
+func (b *Balancer) applied(nodes []selector.WeightedNode) *ring {
+	if r, ok := b.ring.Load().(*ring); ok {
+		return r
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if r, ok := b.ring.Load().(*ring); ok {
+		return r
+	}
+	applied := make([]selector.Node, len(nodes))
+	for i, n := range nodes {
+		applied[i] = n
+	}
+	r := b.build(nil, applied)
+	b.ring.Store(r)
+	return r
+}
+
+// Pick is pick the node of the hash key.
+func (b *Balancer) Pick(ctx context.Context, nodes []selector.WeightedNode) (selector.WeightedNode, selector.DoneFunc, error) {
+	if len(nodes) == 0 {
+		return nil, nil, selector.ErrNoAvailable
+	}
+	key, ok := b.key(ctx)
+	if !ok {
+		selected := nodes[rand.Intn(len(nodes))]
+		return selected, selected.Pick(), nil
+	}
+	candidates := make(map[string]selector.WeightedNode, len(nodes))
+	for _, n := range nodes {
+		candidates[n.Address()] = n
+	}
+	selected, ok := b.applied(nodes).lookup(hash(key), candidates)
+	if !ok {
+		// none of the candidates is applied to the ring.
+		return nil, nil, selector.ErrNoAvailable
+	}
+	return selected, selected.Pick(), nil
+}
+
+func (b *Balancer) key(ctx context.Context) (string, bool) {
+	if key, ok := FromContext(ctx); ok {
+		return key, true
+	}
+	if b.metadataKey != "" {
+		if md, ok := metadata.FromClientContext(ctx); ok {
+			if key := md.Get(b.metadataKey); key != "" {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// build returns the ring of the nodes, reusing the virtual nodes of the old ring.
+func (b *Balancer) build(old *ring, nodes []selector.Node) *ring {
+	r := &ring{nodes: make(map[string][]uint64, len(nodes))}
+	for _, n := range nodes {
+		addr := n.Address()
+		if _, ok := r.nodes[addr]; ok {
+			continue
+		}
+		hashes, ok := old.vnodes(addr)
+		if !ok {
+			hashes = make([]uint64, b.replicas)
+			for i := range hashes {
+				hashes[i] = hash(addr + "#" + strconv.Itoa(i))
+			}
+		}
+		r.nodes[addr] = hashes
+	}
+	type vnode struct {
+		hash uint64
+		addr string
+	}
+	vnodes := make([]vnode, 0, len(r.nodes)*b.replicas)
+	for addr, hashes := range r.nodes {
+		for _, h := range hashes {
+			vnodes = append(vnodes, vnode{hash: h, addr: addr})
+		}
+	}
+	sort.Slice(vnodes, func(i, j int) bool {
+		if vnodes[i].hash == vnodes[j].hash {
+			return vnodes[i].addr < vnodes[j].addr
+		}
+		return vnodes[i].hash < vnodes[j].hash
+	})
+	r.hashes = make([]uint64, len(vnodes))
+	r.addrs = make([]string, len(vnodes))
+	for i, v := range vnodes {
+		r.hashes[i] = v.hash
+		r.addrs[i] = v.addr
+	}
+	return r
+}
+
+func (r *ring) vnodes(addr string) ([]uint64, bool) {
+	if r == nil {
+		return nil, false
+	}
+	hashes, ok := r.nodes[addr]
+	return hashes, ok
+}
+
+// lookup returns the first candidate clockwise from the hash on the ring.
+func (r *ring) lookup(h uint64, candidates map[string]selector.WeightedNode) (selector.WeightedNode, bool) {
+	if r == nil || len(r.hashes) == 0 {
+		return nil, false
+	}
+	start := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	for i := 0; i < len(r.hashes); i++ {
+		if n, ok := candidates[r.addrs[(start+i)%len(r.hashes)]]; ok {
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	// the murmur3 finalizer spreads the similar strings, such as the virtual nodes, over the ring.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// NewBuilder returns a selector builder with ringhash balancer
+func NewBuilder(opts ...Option) selector.Builder {
+	option := options{replicas: defaultReplicas}
+	for _, opt := range opts {
+		opt(&option)
+	}
+	return &selector.DefaultBuilder{
+		Filters:  option.filters,
+		Balancer: &Builder{metadataKey: option.metadataKey, replicas: option.replicas},
+		Node:     &direct.Builder{},
+	}
+}
+
+// Builder is ringhash builder
+type Builder struct {
+	metadataKey string
+	replicas    int
+}
+
+// Build creates Balancer
+func (b *Builder) Build() selector.Balancer {
+	replicas := b.replicas
+	if replicas < 1 {
+		replicas = defaultReplicas
+	}
+	return &Balancer{metadataKey: b.metadataKey, replicas: replicas}
+}
@@ -0,0 +1,155 @@
+package ringhash
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/metadata"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/filter"
+	"github.com/go-kratos/kratos/v2/selector/node/direct"
+	"github.com/stretchr/testify/assert"
+)
+
+func newNodes(n int) []selector.Node {
+	nodes := make([]selector.Node, 0, n)
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.1:%d", 8000+i)
+		nodes = append(nodes, selector.NewNode(addr, &registry.ServiceInstance{ID: addr, Version: "v" + strconv.Itoa(i)}))
+	}
+	return nodes
+}
+
+func pick(t *testing.T, s selector.Selector, key string, opts ...selector.SelectOption) string {
+	t.Helper()
+	n, done, err := s.Select(NewContext(context.Background(), key), opts...)
+	assert.NoError(t, err)
+	done(context.Background(), selector.DoneInfo{})
+	return n.Address()
+}
+
+func TestRingHash(t *testing.T) {
+	s := New()
+	nodes := newNodes(5)
+	s.Apply(nodes)
+
+	picked := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		key := "user-" + strconv.Itoa(i)
+		addr := pick(t, s, key)
+		assert.Equal(t, addr, pick(t, s, key))
+		picked[key] = addr
+		counts[addr]++
+	}
+	assert.Len(t, counts, 5)
+	for addr, c := range counts {
+		assert.Greater(t, c, 1000, addr)
+		assert.Less(t, c, 3000, addr)
+	}
+
+	// only the keys of the removed node move.
+	removed := nodes[2].Address()
+	s.Apply(append(nodes[:2:2], nodes[3:]...))
+	for key, addr := range picked {
+		if addr != removed {
+			assert.Equal(t, addr, pick(t, s, key))
+		} else {
+			assert.NotEqual(t, removed, pick(t, s, key))
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s := New()
+	nodes := newNodes(3)
+	s.Apply(nodes)
+	key := "user-1"
+	addr := pick(t, s, key)
+	var version string
+	for _, n := range nodes {
+		if n.Address() == addr {
+			version = n.Version()
+		}
+	}
+	// the filtered node is skipped for the next one on the ring.
+	other := pick(t, s, key, selector.WithFilter(func(_ context.Context, nodes []selector.Node) []selector.Node {
+		newNodes := make([]selector.Node, 0, len(nodes))
+		for _, n := range nodes {
+			if n.Version() != version {
+				newNodes = append(newNodes, n)
+			}
+		}
+		return newNodes
+	}))
+	assert.NotEqual(t, addr, other)
+	s = New(WithFilter(filter.Version(version)))
+	s.Apply(nodes)
+	assert.Equal(t, addr, pick(t, s, "user-2"))
+}
+
+func TestMetadataKey(t *testing.T) {
+	s := New(WithMetadataKey("x-md-global-uid"))
+	s.Apply(newNodes(5))
+	ctx := metadata.AppendToClientContext(context.Background(), "x-md-global-uid", "kratos")
+	n, _, err := s.Select(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, pick(t, s, "kratos"), n.Address())
+
+	// the requests without a key are picked a random node.
+	n, _, err = s.Select(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, n)
+}
+
+func TestApply(t *testing.T) {
+	b := &Balancer{replicas: 10}
+	nodes := newNodes(2)
+	b.Apply(nodes)
+	old := b.ring.Load().(*ring)
+	assert.Len(t, old.hashes, 20)
+	b.Apply(newNodes(3))
+	r := b.ring.Load().(*ring)
+	assert.Len(t, r.hashes, 30)
+	// the virtual nodes of the unchanged nodes are reused.
+	assert.Equal(t, &old.nodes[nodes[0].Address()][0], &r.nodes[nodes[0].Address()][0])
+
+	_, _, err := b.Pick(context.Background(), nil)
+	assert.Equal(t, selector.ErrNoAvailable, err)
+}
+
+func TestWithReplicas(t *testing.T) {
+	for _, n := range []int{-1, 0} {
+		o := &options{}
+		WithReplicas(n)(o)
+		assert.Equal(t, 1, o.replicas)
+
+		s := New(WithReplicas(n))
+		s.Apply(newNodes(2))
+		assert.NotEmpty(t, pick(t, s, "kratos"))
+	}
+}
+
+func TestPickRing(t *testing.T) {
+	b := &Balancer{replicas: 10}
+	var weighted []selector.WeightedNode
+	for _, n := range newNodes(3) {
+		weighted = append(weighted, (&direct.Builder{}).Build(n))
+	}
+	ctx := NewContext(context.Background(), "kratos")
+	// the ring is built once when no nodes are applied.
+	_, _, err := b.Pick(ctx, weighted)
+	assert.NoError(t, err)
+	r := b.ring.Load().(*ring)
+	_, _, err = b.Pick(ctx, weighted[:1])
+	assert.NoError(t, err)
+	assert.Same(t, r, b.ring.Load().(*ring))
+
+	// the candidates not applied to the ring are not available.
+	b.Apply(newNodes(1))
+	_, _, err = b.Pick(ctx, weighted[1:])
+	assert.Equal(t, selector.ErrNoAvailable, err)
+}
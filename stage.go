@@ -0,0 +1,109 @@
+package kratos
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"golang.org/x/sync/errgroup"
+)
+
+// stagedServer is a server started in a stage.
+type stagedServer struct {
+	stage int
+	srv   transport.Server
+}
+
+// stage is a group of servers started and stopped together.
+type stage struct {
+	n       int
+	servers []transport.Server
+}
+
+// stages returns the stages of the servers in ascending order.
+func (a *App) stages() []*stage {
+	byStage := make(map[int]*stage)
+	for _, srv := range a.opts.servers {
+		n := a.stageOf(srv)
+		st, ok := byStage[n]
+		if !ok {
+			st = &stage{n: n}
+			byStage[n] = st
+		}
+		st.servers = append(st.servers, srv)
+	}
+	stages := make([]*stage, 0, len(byStage))
+	for _, st := range byStage {
+		stages = append(stages, st)
+	}
+	sort.Slice(stages, func(i, j int) bool {
+		return stages[i].n < stages[j].n
+	})
+	return stages
+}
+
+// stageOf returns the first stage the server is passed to with Stage,
+// the servers only passed to Server are in stage 0.
+func (a *App) stageOf(srv transport.Server) int {
+	for _, s := range a.opts.staged {
+		if s.srv == srv {
+			return s.stage
+		}
+	}
+	return 0
+}
+
+func containsServer(servers []transport.Server, srv transport.Server) bool {
+	for _, s := range servers {
+		if s == srv {
+			return true
+		}
+	}
+	return false
+}
+
+// stopStages stops the stages in descending order, bounded by the stop timeout,
+// and returns the first error.
+func (a *App) stopStages(stages []*stage) (err error) {
+	ctx, cancel := a.stopContext()
+	defer cancel()
+	for i := len(stages) - 1; i >= 0; i-- {
+		if serr := a.stopStage(ctx, stages[i]); serr != nil {
+			a.opts.logger.Errorf("failed to stop stage %d: %v", stages[i].n, serr)
+			if err == nil {
+				err = serr
+			}
+		}
+	}
+	return err
+}
+
+// stopStage stops the servers of the stage concurrently, bounded by the stage timeout.
+// The next stage is stopped once the timeout fires, even if the servers are still stopping.
+func (a *App) stopStage(ctx context.Context, st *stage) error {
+	if a.opts.stageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.opts.stageTimeout)
+		defer cancel()
+	}
+	if len(a.opts.staged) > 0 {
+		a.opts.logger.Infof("app stopping stage %d", st.n)
+	}
+	var eg errgroup.Group
+	for _, srv := range st.servers {
+		srv := srv
+		eg.Go(func() error {
+			return srv.Stop(ctx)
+		})
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- eg.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,126 @@
+package kratos
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stageRecorder struct {
+	lk     sync.Mutex
+	events []string
+}
+
+func (r *stageRecorder) record(event string) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.events = append(r.events, event)
+}
+
+type stageServer struct {
+	name     string
+	recorder *stageRecorder
+	ready    chan struct{}
+	block    bool
+}
+
+func newStageServer(name string, recorder *stageRecorder) *stageServer {
+	return &stageServer{name: name, recorder: recorder, ready: make(chan struct{})}
+}
+
+func (s *stageServer) Start(ctx context.Context) error {
+	s.recorder.record("start " + s.name)
+	close(s.ready)
+	<-ctx.Done()
+	return nil
+}
+
+func (s *stageServer) Stop(ctx context.Context) error {
+	s.recorder.record("stop " + s.name)
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (s *stageServer) Ready() <-chan struct{} {
+	return s.ready
+}
+
+func TestApp_Stages(t *testing.T) {
+	recorder := &stageRecorder{}
+	var app *App
+	app = New(
+		Stage(10, newStageServer("worker", recorder)),
+		Stage(-1, newStageServer("admin", recorder)),
+		Server(newStageServer("grpc", recorder)),
+		AfterStart(func(ctx context.Context) error {
+			go func() { _ = app.Stop() }()
+			return nil
+		}),
+	)
+	assert.NoError(t, app.Run())
+	assert.Equal(t, []string{
+		"start admin", "start grpc", "start worker",
+		"stop worker", "stop grpc", "stop admin",
+	}, recorder.events)
+}
+
+func TestApp_StagesDedupe(t *testing.T) {
+	recorder := &stageRecorder{}
+	grpc := newStageServer("grpc", recorder)
+	worker := newStageServer("worker", recorder)
+	var app *App
+	app = New(
+		Server(grpc, worker, grpc),
+		Stage(1, worker),
+		Stage(2, worker),
+		AfterStart(func(ctx context.Context) error {
+			go func() { _ = app.Stop() }()
+			return nil
+		}),
+	)
+	assert.NoError(t, app.Run())
+	// each server is started and stopped once, in its first stage.
+	assert.Equal(t, []string{"start grpc", "start worker", "stop worker", "stop grpc"}, recorder.events)
+	assert.Equal(t, 1, app.stageOf(worker))
+}
+
+func TestApp_StageTimeout(t *testing.T) {
+	recorder := &stageRecorder{}
+	worker := newStageServer("worker", recorder)
+	worker.block = true
+	var app *App
+	app = New(
+		Server(newStageServer("grpc", recorder)),
+		Stage(1, worker),
+		StageTimeout(50*time.Millisecond),
+		StopTimeout(time.Second),
+		AfterStart(func(ctx context.Context) error {
+			go func() { _ = app.Stop() }()
+			return nil
+		}),
+	)
+	start := time.Now()
+	_ = app.Run()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, []string{"start grpc", "start worker", "stop worker", "stop grpc"}, recorder.events)
+}
+
+func TestStage(t *testing.T) {
+	o := &options{}
+	v := &mockServer{}
+	Stage(1, v)(o)
+	assert.Equal(t, []stagedServer{{stage: 1, srv: v}}, o.staged)
+}
+
+func TestStageTimeout(t *testing.T) {
+	o := &options{}
+	v := time.Duration(123)
+	StageTimeout(v)(o)
+	assert.Equal(t, v, o.stageTimeout)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/go-kratos/kratos/v2/selector"
 	"github.com/go-kratos/kratos/v2/selector/p2c"
 	"github.com/go-kratos/kratos/v2/selector/random"
+	"github.com/go-kratos/kratos/v2/selector/ringhash"
 	"github.com/go-kratos/kratos/v2/selector/wrr"
 	"github.com/go-kratos/kratos/v2/transport"
 
@@ -27,6 +28,7 @@ func init() {
 	SetGlobalBalancer(random.Name, random.NewBuilder())
 	SetGlobalBalancer(wrr.Name, wrr.NewBuilder())
 	SetGlobalBalancer(p2c.Name, p2c.NewBuilder())
+	SetGlobalBalancer(ringhash.Name, ringhash.NewBuilder())
 }
 
 // SetGlobalBalancer set grpc balancer with scheme.
@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/ringhash"
 	"github.com/stretchr/testify/assert"
+	gBalancer "google.golang.org/grpc/balancer"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -22,6 +24,10 @@ func TestBalancerName(t *testing.T) {
 	assert.Equal(t, "p2c", o.balancerName)
 }
 
+func TestGlobalBalancer(t *testing.T) {
+	assert.NotNil(t, gBalancer.Get(ringhash.Name))
+}
+
 func TestFilters(t *testing.T) {
 	o := &clientOptions{}
 
@@ -5,6 +5,8 @@ import (
 	"crypto/tls"
 	"net"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
@@ -27,6 +29,8 @@ import (
 var (
 	_ transport.Server     = (*Server)(nil)
 	_ transport.Endpointer = (*Server)(nil)
+	_ transport.Readier    = (*Server)(nil)
+	_ transport.Tracker    = (*Server)(nil)
 )
 
 // ServerOption is gRPC server option.
@@ -112,6 +116,9 @@ type Server struct {
 	grpcOpts   []grpc.ServerOption
 	health     *health.Server
 	metadata   *apimd.Server
+	ready      chan struct{}
+	readyOnce  sync.Once
+	active     int32
 }
 
 // NewServer creates a gRPC server by options.
@@ -122,6 +129,7 @@ func NewServer(opts ...ServerOption) *Server {
 		address: ":0",
 		timeout: 1 * time.Second,
 		health:  health.NewServer(),
+		ready:   make(chan struct{}),
 		log:     log.NewHelper(log.DefaultLogger),
 	}
 	for _, o := range opts {
@@ -171,9 +179,21 @@ func (s *Server) Start(ctx context.Context) error {
 	s.baseCtx = ctx
 	s.log.Infof("[gRPC] server listening on: %s", s.lis.Addr().String())
 	s.health.Resume()
+	// the listener is bound already, the connections are queued until served.
+	s.readyOnce.Do(func() { close(s.ready) })
 	return s.Serve(s.lis)
 }
 
+// Ready returns a channel closed once the gRPC server is serving.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// ActiveRequests returns the number of in-flight unary requests.
+func (s *Server) ActiveRequests() int {
+	return int(atomic.LoadInt32(&s.active))
+}
+
 // Stop stop the gRPC server.
 func (s *Server) Stop(ctx context.Context) error {
 	s.GracefulStop()
@@ -201,6 +221,8 @@ func (s *Server) listenAndEndpoint() error {
 
 func (s *Server) unaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt32(&s.active, 1)
+		defer atomic.AddInt32(&s.active, -1)
 		ctx, cancel := ic.Merge(ctx, s.baseCtx)
 		defer cancel()
 		md, _ := grpcmd.FromIncomingContext(ctx)
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kratos/kratos/v2/health"
+	"github.com/go-kratos/kratos/v2/internal/httputil"
+)
+
+// Health returns the health reported by the server, nil when the Health option is not given.
+func (s *Server) Health() *health.Health {
+	return s.health
+}
+
+// healthHandler writes the health result, with 503 status code when it is not serving.
+func (s *Server) healthHandler(check func(context.Context) *health.Result) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := check(r.Context())
+		codec, _ := CodecForRequest(r, "Accept")
+		data, err := codec.Marshal(res)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", httputil.ContentType(codec.Name()))
+		if !res.Serving() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write(data)
+	}
+}
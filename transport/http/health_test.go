@@ -0,0 +1,55 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/health"
+)
+
+func TestServer_Health(t *testing.T) {
+	h := health.New()
+	srv := NewServer(Health(h))
+	defer srv.lis.Close()
+	assert.Equal(t, h, srv.Health())
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	assert.Equal(t, http.StatusOK, serve(health.LivePath).Code)
+	assert.Equal(t, http.StatusOK, serve(health.ReadyPath).Code)
+	h.SetReady(false)
+	w := serve(health.ReadyPath)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"status":"NOT_SERVING"}`, w.Body.String())
+
+	h.SetReady(true)
+	assert.Equal(t, http.StatusOK, serve(health.ReadyPath).Code)
+	h.AddChecker("db", func(ctx context.Context) error { return errors.New("db is down") })
+	w = serve(health.ReadyPath)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"NOT_SERVING","checks":{"db":"db is down"}}`, w.Body.String())
+}
+
+func TestServer_HealthDisabled(t *testing.T) {
+	srv := NewServer()
+	defer srv.lis.Close()
+	assert.Nil(t, srv.Health())
+	srv.HandleFunc(health.LivePath, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("user"))
+	})
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, health.LivePath, nil))
+	assert.Equal(t, "user", w.Body.String())
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, health.ReadyPath, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
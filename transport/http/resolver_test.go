@@ -8,7 +8,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/health"
 	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/registry/memory"
 	"github.com/go-kratos/kratos/v2/selector"
 	"github.com/stretchr/testify/assert"
 )
@@ -89,3 +91,36 @@ func TestResolver(t *testing.T) {
 	_, err = newResolver(context.Background(), &mockDiscoverys{false}, ta, &mockRebalancer{}, true, true)
 	assert.Nil(t, err)
 }
+
+func TestDiscoveryResolver(t *testing.T) {
+	ctx := context.Background()
+	r := memory.New()
+	servers := make([]*Server, 2)
+	instances := make([]*registry.ServiceInstance, 2)
+	for i := range servers {
+		servers[i] = NewServer(Address("127.0.0.1:0"), Health(health.New()))
+		go func(srv *Server) {
+			_ = srv.Start(ctx)
+		}(servers[i])
+		<-servers[i].Ready()
+		e, err := servers[i].Endpoint()
+		assert.NoError(t, err)
+		instances[i] = &registry.ServiceInstance{ID: strconv.Itoa(i), Name: "helloworld", Endpoints: []string{e.String()}}
+	}
+	defer servers[1].Stop(ctx)
+	assert.NoError(t, r.Register(ctx, instances[0]))
+
+	client, err := NewClient(ctx, WithEndpoint("discovery:///helloworld"), WithDiscovery(r), WithBlock())
+	assert.NoError(t, err)
+	defer client.Close()
+	reply := &health.Result{}
+	assert.NoError(t, client.Invoke(ctx, "GET", health.ReadyPath, nil, reply))
+	assert.True(t, reply.Serving())
+
+	// the instances churn, the client follows the registry.
+	r.Set("helloworld", instances[1])
+	assert.NoError(t, servers[0].Stop(ctx))
+	assert.Eventually(t, func() bool {
+		return client.Invoke(ctx, "GET", health.ReadyPath, nil, &health.Result{}) == nil
+	}, time.Second, 10*time.Millisecond)
+}
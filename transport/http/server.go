@@ -7,8 +7,11 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/health"
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
 
 	"github.com/go-kratos/kratos/v2/internal/host"
@@ -22,6 +25,9 @@ import (
 var (
 	_ transport.Server     = (*Server)(nil)
 	_ transport.Endpointer = (*Server)(nil)
+	_ transport.Readier    = (*Server)(nil)
+	_ transport.Tracker    = (*Server)(nil)
+	_ health.Reporter      = (*Server)(nil)
 )
 
 // ServerOption is an HTTP server option.
@@ -113,6 +119,15 @@ func Listener(lis net.Listener) ServerOption {
 	}
 }
 
+// Health with the health reported by the /healthz and /readyz endpoints,
+// which are only served when it is given. It can be shared with the other
+// servers of the app.
+func Health(h *health.Health) ServerOption {
+	return func(s *Server) {
+		s.health = h
+	}
+}
+
 // Server is an HTTP server wrapper.
 type Server struct {
 	*http.Server
@@ -130,6 +145,10 @@ type Server struct {
 	ene         EncodeErrorFunc
 	strictSlash bool
 	router      *mux.Router
+	health      *health.Health
+	ready       chan struct{}
+	readyOnce   sync.Once
+	active      int32
 	log         *log.Helper
 }
 
@@ -143,6 +162,7 @@ func NewServer(opts ...ServerOption) *Server {
 		enc:         DefaultResponseEncoder,
 		ene:         DefaultErrorEncoder,
 		strictSlash: true,
+		ready:       make(chan struct{}),
 		log:         log.NewHelper(log.DefaultLogger),
 	}
 	for _, o := range opts {
@@ -150,6 +170,10 @@ func NewServer(opts ...ServerOption) *Server {
 	}
 	srv.router = mux.NewRouter().StrictSlash(srv.strictSlash)
 	srv.router.Use(srv.filter())
+	if srv.health != nil {
+		srv.router.HandleFunc(health.LivePath, srv.healthHandler(srv.health.Live))
+		srv.router.HandleFunc(health.ReadyPath, srv.healthHandler(srv.health.Ready))
+	}
 	srv.Server = &http.Server{
 		Handler:   FilterChain(srv.filters...)(srv.router),
 		TLSConfig: srv.tlsConf,
@@ -216,6 +240,8 @@ func (s *Server) filter() mux.MiddlewareFunc {
 				pathTemplate: pathTemplate,
 			}
 			ctx = transport.NewServerContext(ctx, tr)
+			atomic.AddInt32(&s.active, 1)
+			defer atomic.AddInt32(&s.active, -1)
 			next.ServeHTTP(w, req.WithContext(ctx))
 		})
 	}
@@ -240,6 +266,8 @@ func (s *Server) Start(ctx context.Context) error {
 		return ctx
 	}
 	s.log.Infof("[HTTP] server listening on: %s", s.lis.Addr().String())
+	// the listener is bound already, the connections are queued until served.
+	s.readyOnce.Do(func() { close(s.ready) })
 	var err error
 	if s.tlsConf != nil {
 		err = s.ServeTLS(s.lis, "", "")
@@ -252,6 +280,16 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// Ready returns a channel closed once the HTTP server is serving.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// ActiveRequests returns the number of in-flight requests.
+func (s *Server) ActiveRequests() int {
+	return int(atomic.LoadInt32(&s.active))
+}
+
 // Stop stop the HTTP server.
 func (s *Server) Stop(ctx context.Context) error {
 	s.log.Info("[HTTP] server stopping")
@@ -24,6 +24,20 @@ type Endpointer interface {
 	Endpoint() (*url.URL, error)
 }
 
+// Readier is implemented by the servers signaling when they are serving,
+// kratos.App waits for them to be ready before registering the instance.
+type Readier interface {
+	// Ready returns a channel closed once the server accepts connections.
+	Ready() <-chan struct{}
+}
+
+// Tracker is implemented by the servers tracking their in-flight requests,
+// kratos.App reports them while draining.
+type Tracker interface {
+	// ActiveRequests returns the number of in-flight requests.
+	ActiveRequests() int
+}
+
 // Header is the storage medium used by a Header.
 type Header interface {
 	Get(key string) string
@@ -0,0 +1,124 @@
+package xhttp
+
+import (
+	"context"
+	"reflect"
+	"unsafe"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// FromMiddleware wraps kratos middleware as a fiber handler. The request is
+// read through the xhttp Transport of the user context, which is created when
+// the handler is not served by the xhttp Server, and the *fiber.Ctx is passed
+// as the request. Errors returned by the middleware are converted into kratos errors.
+//
+//   app.Use(xhttp.FromMiddleware(recovery.Recovery(), selector.Server(auth).Prefix("/admin").Build()))
+func FromMiddleware(m ...middleware.Middleware) fiber.Handler {
+	chain := middleware.Chain(m...)
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		if _, ok := transport.FromServerContext(ctx); !ok {
+			ctx = transport.NewServerContext(ctx, &Transport{
+				operation:    c.Path(),
+				reqHeader:    NewRequestHeader(c),
+				replyHeader:  NewReplyHeaderCarrier(c),
+				request:      c,
+				pathTemplate: c.Route().Path,
+			})
+		}
+		var (
+			called  bool
+			nextErr error
+		)
+		_, err := chain(func(ctx context.Context, req interface{}) (interface{}, error) {
+			called = true
+			c.SetUserContext(ctx)
+			nextErr = c.Next()
+			return nil, nextErr
+		})(ctx, c)
+		if err == nil || (called && errors.Is(err, nextErr)) {
+			// the errors of the next handlers are left to the error handler.
+			return err
+		}
+		return fromError(err)
+	}
+}
+
+// ToMiddleware lifts a fiber handler into kratos middleware for the xhttp Server.
+// The handler runs on the *fiber.Ctx of the request with the middleware context
+// as the user context, and continues to the kratos handler by calling c.Next.
+// The context it sets with c.SetUserContext is passed on. When the handler ends
+// the request without calling c.Next, its error is returned, or an error with the
+// response status code when it has written an error status.
+func ToMiddleware(h fiber.Handler) middleware.Middleware {
+	return func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, _ := transport.FromServerContext(ctx)
+			xtr, ok := tr.(Transporter)
+			if !ok {
+				return nil, errors.InternalServer(errors.UnknownReason, "fiber handler requires the xhttp server transport")
+			}
+			c := xtr.Request()
+			var (
+				called bool
+				reply  interface{}
+				err    error
+			)
+			// the lifted route keeps the path and params of the request route,
+			// and c.Next of the handler leads to the kratos handler.
+			route := *c.Route()
+			prevRoute, prevIndex := swapHandlers(c, &route, 0)
+			route.Handlers = []fiber.Handler{h, func(c *fiber.Ctx) error {
+				called = true
+				// the kratos handler continues on the request route.
+				swapHandlers(c, prevRoute, prevIndex)
+				reply, err = next(c.UserContext(), req)
+				swapHandlers(c, &route, 1)
+				return err
+			}}
+			c.SetUserContext(ctx)
+			herr := h(c)
+			swapHandlers(c, prevRoute, prevIndex)
+			if called {
+				return reply, err
+			}
+			if herr != nil {
+				return nil, fromError(herr)
+			}
+			if code := c.Response().StatusCode(); code >= fiber.StatusBadRequest {
+				return nil, errors.New(code, errors.UnknownReason, utils.StatusMessage(code))
+			}
+			return nil, nil
+		}
+	}
+}
+
+// ctxRouteOffset and ctxIndexOffset locate the handler chain of fiber.Ctx, which
+// fiber does not export.
+var (
+	ctxRouteOffset = ctxField("route", reflect.TypeOf((*fiber.Route)(nil)))
+	ctxIndexOffset = ctxField("indexHandler", reflect.TypeOf(0))
+)
+
+func ctxField(name string, typ reflect.Type) uintptr {
+	f, ok := reflect.TypeOf(fiber.Ctx{}).FieldByName(name)
+	if !ok || f.Type != typ {
+		panic("xhttp: unsupported fiber.Ctx, the field " + name + " is missing")
+	}
+	return f.Offset
+}
+
+// swapHandlers sets the route and the handler index of c, returning the previous ones.
+func swapHandlers(c *fiber.Ctx, route *fiber.Route, index int) (*fiber.Route, int) {
+	r := (**fiber.Route)(unsafe.Add(unsafe.Pointer(c), ctxRouteOffset))
+	i := (*int)(unsafe.Add(unsafe.Pointer(c), ctxIndexOffset))
+	prevRoute, prevIndex := *r, *i
+	*r, *i = route, index
+	return prevRoute, prevIndex
+}
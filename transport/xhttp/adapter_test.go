@@ -0,0 +1,125 @@
+package xhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/selector"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type ctxKey struct{}
+
+func testGet(t *testing.T, app *fiber.App, path string) (int, string) {
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestFromMiddleware(t *testing.T) {
+	var operations []string
+	deny := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				operations = append(operations, tr.Operation())
+			}
+			return nil, errors.Forbidden("FORBIDDEN", "denied")
+		}
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: DefaultErrorHandler})
+	app.Get("/users/:id", FromMiddleware(selector.Server(deny).Path("/users/:id").Build()), func(c *fiber.Ctx) error {
+		return c.SendString("user " + c.Params("id"))
+	})
+	app.Get("/teams/:id", FromMiddleware(selector.Server(deny).Path("/users/:id").Build()), func(c *fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
+
+	code, body := testGet(t, app, "/users/1")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Contains(t, body, "FORBIDDEN")
+	assert.Equal(t, []string{"/users/1"}, operations)
+
+	code, _ = testGet(t, app, "/teams/1")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestToMiddleware(t *testing.T) {
+	var app *fiber.App
+	lifted := ToMiddleware(func(c *fiber.Ctx) error {
+		app = c.App()
+		if c.Get("x-deny") != "" {
+			return c.Status(http.StatusUnauthorized).SendString("denied")
+		}
+		c.Set("x-fiber", "lifted")
+		c.Set("x-route", c.Route().Path)
+		c.Set("x-name", c.Params("name"))
+		c.SetUserContext(context.WithValue(c.UserContext(), ctxKey{}, "fiber"))
+		return c.Next()
+	})
+	// the lifted handlers nest on the same request.
+	inner := ToMiddleware(func(c *fiber.Ctx) error {
+		if c.Get("x-missing") != "" {
+			return fiber.ErrNotFound
+		}
+		c.Set("x-inner", c.Params("name"))
+		return c.Next()
+	})
+	srv := NewServer(Address("127.0.0.1:0"), KratosMiddleware(lifted, inner))
+	srv.Route(func(r fiber.Router) {
+		r.Get("/users/:name", srv.Handle(func(c *fiber.Ctx) error {
+			_, ok := transport.FromServerContext(c.UserContext())
+			assert.True(t, ok)
+			return c.SendString(c.UserContext().Value(ctxKey{}).(string) + " " + c.Params("name"))
+		}))
+	})
+	defer srv.lis.Close()
+
+	resp, err := srv.server.Test(httptest.NewRequest(http.MethodGet, "/users/kratos", nil))
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "fiber kratos", string(body))
+	assert.Equal(t, "lifted", resp.Header.Get("x-fiber"))
+	assert.Equal(t, "/users/:name", resp.Header.Get("x-route"))
+	assert.Equal(t, "kratos", resp.Header.Get("x-name"))
+	assert.Equal(t, "kratos", resp.Header.Get("x-inner"))
+	assert.Equal(t, srv.server, app)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/kratos", nil)
+	req.Header.Set("x-deny", "true")
+	resp, err = srv.server.Test(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodGet, "/users/kratos", nil)
+	req.Header.Set("x-missing", "true")
+	resp, err = srv.server.Test(req)
+	assert.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	// the error is answered by the error handler of the server.
+	assert.Contains(t, string(body), `"code":404`)
+
+	_, err = lifted(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})(context.Background(), nil)
+	assert.True(t, errors.IsInternalServer(err))
+}
@@ -1,9 +1,15 @@
 package apistate
 
 import (
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/encoding/json"
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/httputil"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/utils"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // Resp	represents the response of the API
@@ -49,16 +55,69 @@ func (r *Resp[T]) WithError(err any) *Resp[T] {
 	return r
 }
 
-// Send sends the response
+// Send sends the response, encoded by the codec negotiated with the Accept header
 func (r *Resp[T]) Send(c *fiber.Ctx) error {
 	// if the response is a kratos error, we send the error
 	if err, ok := r.Error.(*errors.Error); ok {
 		if utils.StatusMessage(int(err.Code)) == "" {
-			return c.Status(fiber.StatusInternalServerError).JSON(err)
+			return send(c, fiber.StatusInternalServerError, err)
 		}
-		return c.Status(int(err.Code)).JSON(err)
+		return send(c, int(err.Code), err)
 	}
-	return c.Status(int(r.Code)).JSON(r)
+	return send(c, int(r.Code), r)
+}
+
+// send encodes v with the request codec, falling back to json
+// if the value is not supported by the codec.
+func send(c *fiber.Ctx, code int, v interface{}) error {
+	codec := CodecForRequest(c)
+	data, err := codec.Marshal(v)
+	if err != nil && codec.Name() != json.Name {
+		codec = encoding.GetCodec(json.Name)
+		data, err = codec.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, httputil.ContentType(codec.Name()))
+	return c.Status(code).Send(data)
+}
+
+// CodecForRequest get encoding.Codec via the Accept header of the request,
+// preferring the media types with the higher quality values.
+func CodecForRequest(c *fiber.Ctx) encoding.Codec {
+	type accepted struct {
+		subtype string
+		q       float64
+	}
+	var accepts []accepted
+	for _, accept := range strings.Split(c.Get(fiber.HeaderAccept), ",") {
+		accept = strings.TrimSpace(accept)
+		q := 1.0
+		for _, param := range strings.Split(accept, ";")[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(k) != "q" {
+				continue
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		// q=0 means the media type is not acceptable
+		if q <= 0 {
+			continue
+		}
+		accepts = append(accepts, accepted{subtype: httputil.ContentSubtype(accept), q: q})
+	}
+	sort.SliceStable(accepts, func(i, j int) bool {
+		return accepts[i].q > accepts[j].q
+	})
+	for _, accept := range accepts {
+		if codec := encoding.GetCodec(accept.subtype); codec != nil {
+			return codec
+		}
+	}
+	return encoding.GetCodec(json.Name)
 }
 
 // Success response
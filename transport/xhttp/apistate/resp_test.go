@@ -2,12 +2,18 @@ package apistate
 
 import (
 	"errors"
-	kerrors "github.com/go-kratos/kratos/v2/errors"
-	"github.com/gofiber/fiber/v2"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
-
-	"log"
 	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/msgpack"
+	_ "github.com/go-kratos/kratos/v2/encoding/xml"
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
 )
 
 type T struct {
@@ -18,6 +24,10 @@ func (t T) Error() string {
 	return strings.Join(t.errs, ";")
 }
 
+type user struct {
+	Name string `json:"name" xml:"name" msgpack:"name"`
+}
+
 func TestResp_Send(t *testing.T) {
 	app := fiber.New()
 
@@ -39,5 +49,61 @@ func TestResp_Send(t *testing.T) {
 		}).Send(c)
 	})
 
-	log.Fatal(app.Listen(":3000"))
+	app.Get("/user", func(c *fiber.Ctx) error {
+		return Success[*user]().WithData(&user{Name: "kratos"}).Send(c)
+	})
+
+	tests := []struct {
+		path        string
+		accept      string
+		code        int
+		contentType string
+		contains    string
+	}{
+		{"/err1", "", http.StatusBadRequest, "application/json", "test error"},
+		{"/err1/1", "", http.StatusInternalServerError, "application/json", "test error"},
+		{"/err2", "", http.StatusInternalServerError, "application/json", "hello noonoo"},
+		{"/success", "", http.StatusOK, "application/json", "kratos success"},
+		{"/success", "text/html, application/json;q=0.9", http.StatusOK, "application/json", "kratos success"},
+		{"/success", "application/msgpack", http.StatusOK, "application/msgpack", "kratos success"},
+		{"/success", "application/msgpack;q=0.1, application/json", http.StatusOK, "application/json", "kratos success"},
+		{"/user", "application/json;q=0.5, application/xml", http.StatusOK, "application/xml", "<name>kratos</name>"},
+		{"/user", "application/xml;q=0, application/json;q=0.1", http.StatusOK, "application/json", "kratos"},
+		// maps are not supported by xml, so json is used
+		{"/success", "application/xml", http.StatusOK, "application/json", "kratos success"},
+		{"/user", "application/xml", http.StatusOK, "application/xml", "<name>kratos</name>"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.accept != "" {
+			req.Header.Set(fiber.HeaderAccept, test.accept)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, test.code, resp.StatusCode, test.path)
+		assert.Equal(t, test.contentType, resp.Header.Get(fiber.HeaderContentType), test.path)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), test.contains, test.path)
+		resp.Body.Close()
+	}
+}
+
+func TestResp_SendMsgPack(t *testing.T) {
+	app := fiber.New()
+	app.Get("/user", func(c *fiber.Ctx) error {
+		return Success[*user]().WithData(&user{Name: "kratos"}).Send(c)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set(fiber.HeaderAccept, "application/msgpack")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var res Resp[*user]
+	assert.NoError(t, encoding.GetCodec("msgpack").Unmarshal(body, &res))
+	assert.Equal(t, int32(http.StatusOK), res.Code)
+	assert.Equal(t, "kratos", res.Metadata.Name)
 }
@@ -1,12 +1,55 @@
 package binding
 
 import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
 	"github.com/go-kratos/kratos/v2/encoding"
 	"github.com/go-kratos/kratos/v2/encoding/form"
-	"github.com/gofiber/fiber/v2"
-	"net/url"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/httputil"
+
+	// init encoding
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	_ "github.com/go-kratos/kratos/v2/encoding/proto"
+	_ "github.com/go-kratos/kratos/v2/encoding/xml"
+	_ "github.com/go-kratos/kratos/v2/encoding/yaml"
+)
+
+const (
+	// reason holds the error reason.
+	reason = "CODEC"
+	// headerTag is the struct tag naming the header bound to a field.
+	headerTag = "header"
 )
 
+// Bind binds the body, query, path params and headers of the request to target,
+// which is a proto message or a struct, with the kratos codecs. The query
+// overrides the body and the path params override both, the same as the
+// transport/http binding, then the headers fill the tagged fields left unset.
+// The body and query are decoded by the field names of the json tags, unlike
+// BindBody and BindQuery using the fiber parsers.
+// The error is a kratos BadRequest error with the failed fields in its metadata.
+func Bind(ctx *fiber.Ctx, target interface{}) error {
+	if len(ctx.Body()) > 0 {
+		if err := bindBody(ctx, target); err != nil {
+			return err
+		}
+	}
+	if err := bindQuery(ctx, target); err != nil {
+		return err
+	}
+	if err := BindParams(ctx, target); err != nil {
+		return err
+	}
+	return BindHeader(ctx, target)
+}
+
 // BindQuery bind query parameters to target.
 func BindQuery(ctx *fiber.Ctx, target interface{}) (err error) {
 	return ctx.QueryParser(target)
@@ -17,11 +60,110 @@ func BindBody(ctx *fiber.Ctx, target interface{}) (err error) {
 	return ctx.BodyParser(target)
 }
 
-// BindParams bind body parameters to target.
-func BindParams(ctx *fiber.Ctx, target interface{}) (err error) {
+// bindQuery binds the query parameters to target with the form codec.
+func bindQuery(ctx *fiber.Ctx, target interface{}) error {
+	vars, err := url.ParseQuery(string(ctx.Request().URI().QueryString()))
+	if err != nil {
+		return errors.BadRequest(reason, err.Error())
+	}
+	return decodeValues(vars, target)
+}
+
+// bindBody binds the body to target with the codec chosen by the Content-Type header.
+func bindBody(ctx *fiber.Ctx, target interface{}) error {
+	contentType := string(ctx.Request().Header.ContentType())
+	codec := encoding.GetCodec(httputil.ContentSubtype(strings.ToLower(contentType)))
+	if codec == nil {
+		return errors.BadRequest(reason, contentType)
+	}
+	if codec.Name() == form.Name {
+		vars, err := url.ParseQuery(string(ctx.Body()))
+		if err != nil {
+			return errors.BadRequest(reason, err.Error())
+		}
+		return decodeValues(vars, target)
+	}
+	if err := codec.Unmarshal(ctx.Body(), target); err != nil {
+		return errors.BadRequest(reason, err.Error())
+	}
+	return nil
+}
+
+// BindHeader bind header parameters to target. Only the struct fields tagged
+// with the header name are bound, for example `header:"X-Request-Id"`, and
+// the fields already set are left as they are.
+func BindHeader(ctx *fiber.Ctx, target interface{}) error {
+	v := reflect.ValueOf(target)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var headers url.Values
+	vars := make(url.Values)
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		name := f.Tag.Get(headerTag)
+		if name == "" || name == "-" || !f.IsExported() || !v.Field(i).IsZero() {
+			continue
+		}
+		if headers == nil {
+			headers = make(url.Values)
+			ctx.Request().Header.VisitAll(func(key, value []byte) {
+				k := http.CanonicalHeaderKey(string(key))
+				headers[k] = append(headers[k], string(value))
+			})
+		}
+		values, ok := headers[http.CanonicalHeaderKey(name)]
+		if !ok {
+			continue
+		}
+		key := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" {
+			key = tag
+		}
+		vars[key] = values
+	}
+	return decodeValues(vars, target)
+}
+
+// BindParams bind path parameters to target.
+func BindParams(ctx *fiber.Ctx, target interface{}) error {
 	vars := make(url.Values, len(ctx.Route().Params))
 	for _, k := range ctx.Route().Params {
 		vars[k] = []string{ctx.Params(k)}
 	}
-	return encoding.GetCodec(form.Name).Unmarshal([]byte(vars.Encode()), target)
+	return decodeValues(vars, target)
+}
+
+// decodeValues decodes vars into target with the form codec. Each key is
+// decoded on its own, so that the fields failed to bind are all reported.
+func decodeValues(vars url.Values, target interface{}) error {
+	codec := encoding.GetCodec(form.Name)
+	var fields map[string]string
+	for k, v := range vars {
+		if err := codec.Unmarshal([]byte(url.Values{k: v}.Encode()), target); err != nil {
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[k] = err.Error()
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	msgs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		msgs = append(msgs, k+": "+fields[k])
+	}
+	return errors.BadRequest(reason, strings.Join(msgs, "; ")).WithMetadata(fields)
 }
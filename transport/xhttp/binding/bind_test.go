@@ -1,49 +1,175 @@
 package binding
 
 import (
-	"github.com/gofiber/fiber/v2"
-	"log"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/internal/testdata/binding"
 )
 
 type HelloRequest struct {
-	Name  string `json:"name" form:"name" validate:"required"`
-	ID    string `json:"id" form:"id" validate:"required"`
-	Phone string `json:"phone" form:"phone"`
+	Name      string `json:"name" form:"name" validate:"required"`
+	ID        string `json:"id" form:"id" validate:"required"`
+	Phone     string `json:"phone" form:"phone"`
+	Age       int    `json:"age" form:"age" query:"age"`
+	RequestID string `json:"x_request_id" header:"X-Request-Id"`
 }
 
-func TestBindQuery(t *testing.T) {
+func testBind(t *testing.T, path string, target func() interface{}, bind func(*fiber.Ctx, interface{}) error, req *http.Request) (interface{}, error) {
+	var (
+		out    interface{}
+		outErr error
+	)
 	app := fiber.New()
-	app.Get("hello", func(ctx *fiber.Ctx) error {
-		var in HelloRequest
-		if err := BindQuery(ctx, &in); err != nil {
-			return err
-		}
-		return ctx.JSON(in)
+	app.All(path, func(ctx *fiber.Ctx) error {
+		out = target()
+		outErr = bind(ctx, out)
+		return nil
 	})
-	log.Fatal(app.Listen(":19000"))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return out, outErr
+}
+
+func newHelloRequest() interface{} {
+	return &HelloRequest{}
+}
+
+func TestBindQuery(t *testing.T) {
+	out, err := testBind(t, "/hello", newHelloRequest, BindQuery,
+		httptest.NewRequest(http.MethodGet, "/hello?name=kratos&id=1&age=18", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, &HelloRequest{Name: "kratos", ID: "1", Age: 18}, out)
 }
 
 func TestBindBody(t *testing.T) {
-	app := fiber.New()
-	app.Get("hello", func(ctx *fiber.Ctx) error {
-		var in HelloRequest
-		if err := BindBody(ctx, &in); err != nil {
-			return err
-		}
-		return ctx.JSON(in)
-	})
-	log.Fatal(app.Listen(":19000"))
+	body, _ := json.Marshal(&HelloRequest{Name: "kratos", Phone: "123"})
+	req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	out, err := testBind(t, "/hello", newHelloRequest, BindBody, req)
+	assert.NoError(t, err)
+	assert.Equal(t, &HelloRequest{Name: "kratos", Phone: "123"}, out)
+
+	req = httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader("name=kratos&age=18"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	out, err = testBind(t, "/hello", newHelloRequest, BindBody, req)
+	assert.NoError(t, err)
+	assert.Equal(t, &HelloRequest{Name: "kratos", Age: 18}, out)
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	_ = w.WriteField("name", "kratos")
+	_ = w.WriteField("phone", "123")
+	_ = w.Close()
+	req = httptest.NewRequest(http.MethodPost, "/hello", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	out, err = testBind(t, "/hello", newHelloRequest, BindBody, req)
+	assert.NoError(t, err)
+	assert.Equal(t, &HelloRequest{Name: "kratos", Phone: "123"}, out)
+
+	req = httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader("kratos"))
+	req.Header.Set("Content-Type", "text/plain")
+	_, err = testBind(t, "/hello", newHelloRequest, BindBody, req)
+	assert.Error(t, err)
+}
+
+func TestBindHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	out, err := testBind(t, "/hello", newHelloRequest, BindHeader, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", out.(*HelloRequest).RequestID)
+
+	// the untagged fields are not bound from the headers.
+	req = httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.Header.Set("Name", "header")
+	out, err = testBind(t, "/hello", newHelloRequest, BindHeader, req)
+	assert.NoError(t, err)
+	assert.Equal(t, &HelloRequest{}, out)
 }
 
 func TestBindParams(t *testing.T) {
-	app := fiber.New()
-	app.Get("hello/:name/:phone/:id", func(ctx *fiber.Ctx) error {
-		var in HelloRequest
-		if err := BindParams(ctx, &in); err != nil {
-			return err
-		}
-		return ctx.JSON(in)
-	})
-	log.Fatal(app.Listen(":19000"))
+	out, err := testBind(t, "/hello/:name/:phone/:id", newHelloRequest, BindParams,
+		httptest.NewRequest(http.MethodGet, "/hello/kratos/123/1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, &HelloRequest{Name: "kratos", ID: "1", Phone: "123"}, out)
+}
+
+func TestBind(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/hello/kratos?name=query&phone=123",
+		strings.NewReader(`{"name":"body","id":"1","age":18}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "abc")
+	// only the tagged fields are bound from the headers.
+	req.Header.Set("Id", "header")
+	req.Header.Set("Phone", "header")
+	req.Header.Set("Name", "header")
+	out, err := testBind(t, "/hello/:name", newHelloRequest, Bind, req)
+	assert.NoError(t, err)
+	assert.Equal(t, &HelloRequest{Name: "kratos", ID: "1", Phone: "123", Age: 18, RequestID: "abc"}, out)
+
+	req = httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader("name=kratos&age=18"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	out, err = testBind(t, "/hello", newHelloRequest, Bind, req)
+	assert.NoError(t, err)
+	assert.Equal(t, &HelloRequest{Name: "kratos", Age: 18}, out)
+
+	// the headers do not override the fields sent in the body.
+	req = httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader(`{"x_request_id":"body"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "header")
+	out, err = testBind(t, "/hello", newHelloRequest, Bind, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "body", out.(*HelloRequest).RequestID)
+
+	req = httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader("kratos"))
+	req.Header.Set("Content-Type", "text/plain")
+	_, err = testBind(t, "/hello", newHelloRequest, Bind, req)
+	assert.True(t, errors.IsBadRequest(err))
+}
+
+func TestBindProto(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/hello/kratos?sub.naming=query",
+		strings.NewReader(`{"name":"body","sub":{"naming":"body"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	out, err := testBind(t, "/hello/:name", func() interface{} { return &binding.HelloRequest{} }, Bind, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "kratos", out.(*binding.HelloRequest).Name)
+	assert.Equal(t, "query", out.(*binding.HelloRequest).Sub.Name)
+
+	// the body decoding resets the message, the headers are bound after it and
+	// do not override its fields.
+	req = httptest.NewRequest(http.MethodPost, "/hello",
+		strings.NewReader(`{"name":"body","sub":{"naming":"body"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Name", "header")
+	req.Header.Set("Host", "kratos.dev")
+	out, err = testBind(t, "/hello", func() interface{} { return &binding.HelloRequest{} }, Bind, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "body", out.(*binding.HelloRequest).Name)
+	assert.Equal(t, "body", out.(*binding.HelloRequest).Sub.Name)
+}
+
+func TestBindFieldErrors(t *testing.T) {
+	_, err := testBind(t, "/hello", newHelloRequest, Bind,
+		httptest.NewRequest(http.MethodGet, "/hello?age=old&name=kratos", nil))
+	se := errors.FromError(err)
+	assert.Equal(t, int32(http.StatusBadRequest), se.Code)
+	assert.Equal(t, "CODEC", se.Reason)
+	assert.Contains(t, se.Metadata, "age")
+	assert.NotContains(t, se.Metadata, "name")
 }
@@ -8,10 +8,14 @@ import (
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/internal/host"
 	"github.com/go-kratos/kratos/v2/internal/httputil"
+	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/registry"
 	"github.com/go-kratos/kratos/v2/selector"
 	"github.com/go-kratos/kratos/v2/selector/wrr"
+	"github.com/go-kratos/kratos/v2/transport"
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"net/http"
 	"time"
 )
 
@@ -24,32 +28,13 @@ type EncodeRequestFunc func(ctx context.Context, contentType string, in interfac
 // DecodeResponseFunc is response decode func.
 type DecodeResponseFunc func(ctx context.Context, resp *Response, out interface{}) error
 
-// callOptions is call options.
-type callOption struct {
-	path        string
-	method      string
-	contentType string
-}
-
+// Response is the FIBER response of a call.
 type Response struct {
 	Code        int
 	Body        []byte
 	Errors      []error
 	ContentType string
-}
-
-// defaultCallOptions is the default set of call options.
-func defaultCallOption(path, method string) callOption {
-	return callOption{
-		path:        path,
-		method:      method,
-		contentType: fiber.MIMEApplicationJSON,
-	}
-}
-
-// SetContentType sets the content type for a call option.
-func (o *callOption) SetContentType(contentType string) {
-	o.contentType = contentType
+	Header      http.Header
 }
 
 // ClientOption is FIBER client option.
@@ -67,7 +52,12 @@ type clientOptions struct {
 	errorDecoder DecodeErrorFunc
 	selector     selector.Selector
 	discovery    registry.Discovery
+	middleware   []middleware.Middleware
+	filters      []selector.Filter
 	block        bool
+
+	maxConnsPerHost    int
+	maxConnWaitTimeout time.Duration
 }
 
 // WithTimeout with client request timeout.
@@ -105,6 +95,13 @@ func WithUserAgent(ua string) ClientOption {
 	}
 }
 
+// WithMiddleware with client middleware.
+func WithMiddleware(m ...middleware.Middleware) ClientOption {
+	return func(o *clientOptions) {
+		o.middleware = m
+	}
+}
+
 // WithEndpoint with client addr.
 func WithEndpoint(endpoint string) ClientOption {
 	return func(o *clientOptions) {
@@ -126,6 +123,13 @@ func WithSelector(selector selector.Selector) ClientOption {
 	}
 }
 
+// WithFilter with select filters
+func WithFilter(filters ...selector.Filter) ClientOption {
+	return func(o *clientOptions) {
+		o.filters = filters
+	}
+}
+
 // WithBlock with client block.
 func WithBlock() ClientOption {
 	return func(o *clientOptions) {
@@ -140,12 +144,29 @@ func WithTLSConfig(c *tls.Config) ClientOption {
 	}
 }
 
+// WithMaxConnsPerHost with the maximum number of the pooled connections per host,
+// default is fasthttp.DefaultMaxConnsPerHost.
+func WithMaxConnsPerHost(n int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxConnsPerHost = n
+	}
+}
+
+// WithMaxConnWaitTimeout with the time a request waits for a free connection once
+// the host has the maximum number of connections, default is the client timeout.
+// The request fails immediately with fasthttp.ErrNoFreeConns when it is 0.
+func WithMaxConnWaitTimeout(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.maxConnWaitTimeout = d
+	}
+}
+
 // Client is an HTTP client.
 type Client struct {
 	opts     clientOptions
 	target   *Target
 	r        *resolver
-	cc       *fiber.Agent
+	cc       *fasthttp.Client
 	insecure bool
 }
 
@@ -158,10 +179,16 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 		decoder:      DefaultResponseDecoder,
 		errorDecoder: DefaultErrorDecoder,
 		selector:     wrr.New(),
+
+		maxConnsPerHost:    fasthttp.DefaultMaxConnsPerHost,
+		maxConnWaitTimeout: -1,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
+	if options.maxConnWaitTimeout < 0 {
+		options.maxConnWaitTimeout = options.timeout
+	}
 
 	insecure := options.tlsConf == nil
 	target, err := parseTarget(options.endpoint, insecure)
@@ -178,68 +205,80 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 			return nil, fmt.Errorf("[fiber client] invalid endpoint format: %v", options.endpoint)
 		}
 	}
-	agent := fiber.AcquireAgent()
-	agent.JSONEncoder(encoding.GetCodec("json").Marshal)
-	agent.JSONDecoder(encoding.GetCodec("json").Unmarshal)
-	agent.Timeout(options.timeout)
 	return &Client{
 		opts:     options,
 		target:   target,
 		insecure: insecure,
 		r:        r,
-		cc:       agent,
+		// the client is safe for concurrent use, connections are pooled per host.
+		cc: &fasthttp.Client{
+			TLSConfig:          options.tlsConf,
+			MaxConnsPerHost:    options.maxConnsPerHost,
+			MaxConnWaitTimeout: options.maxConnWaitTimeout,
+		},
 	}, nil
 }
 
 // Invoke makes an rpc call procedure for remote service.
-func (client *Client) Invoke(ctx context.Context, method, path string, args interface{}, reply interface{}) (err error) {
-	c := defaultCallOption(path, method)
-	agent := client.cc
-	var body []byte
-	req := agent.Request()
-	switch c.method {
-	case fiber.MethodGet:
-		req.Header.SetMethod(fiber.MethodGet)
-	case fiber.MethodPost:
-		req.Header.SetMethod(fiber.MethodPost)
-	case fiber.MethodPut:
-		req.Header.SetMethod(fiber.MethodPut)
-	case fiber.MethodDelete:
-		req.Header.SetMethod(fiber.MethodDelete)
-	case fiber.MethodPatch:
-		req.Header.SetMethod(fiber.MethodPatch)
+func (client *Client) Invoke(ctx context.Context, method, path string, args interface{}, reply interface{}, opts ...CallOption) error {
+	switch method {
+	case fiber.MethodGet, fiber.MethodPost, fiber.MethodPut, fiber.MethodDelete, fiber.MethodPatch:
 	default:
-		return errors.BadRequest("[fiber client] invalid method: %s", c.method)
+		return errors.BadRequest("INVALID_METHOD", fmt.Sprintf("[fiber client] invalid method: %s", method))
 	}
+	c := defaultCallInfo(path)
+	for _, o := range opts {
+		if err := o.before(&c); err != nil {
+			return err
+		}
+	}
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.Header.SetMethod(method)
 	if args != nil {
-		body, err = client.opts.encoder(ctx, c.contentType, args)
+		body, err := client.opts.encoder(ctx, c.contentType, args)
 		if err != nil {
 			return err
 		}
+		req.SetBody(body)
+		req.Header.SetContentType(c.contentType)
 	}
-	var url string
-	if client.insecure {
-		url = fmt.Sprintf("%s://%s%s", "http", client.target.Authority, path)
-	} else {
-		url = fmt.Sprintf("%s://%s%s", "https", client.target.Authority, path)
-	}
-	req.SetRequestURI(url)
-	agent.Body(body)
-	if err != nil {
-		return err
-	}
-	if c.contentType != "" {
-		agent.ContentType(c.contentType)
+	scheme := "http"
+	if !client.insecure {
+		scheme = "https"
 	}
+	req.SetRequestURI(fmt.Sprintf("%s://%s%s", scheme, client.target.Authority, path))
 	if client.opts.userAgent != "" {
-		agent.UserAgent(client.opts.userAgent)
-	}
-	return client.invoke(ctx, c, args, reply)
+		req.Header.SetUserAgent(client.opts.userAgent)
+	}
+	ctx = transport.NewClientContext(ctx, &ClientTransport{
+		endpoint:     client.opts.endpoint,
+		reqHeader:    clientHeaderCarrier{header: &req.Header},
+		replyHeader:  headerCarrier{},
+		operation:    c.operation,
+		request:      req,
+		pathTemplate: c.pathTemplate,
+		filters:      client.opts.filters,
+	})
+	return client.invoke(ctx, req, args, reply, c, opts...)
 }
 
-func (client *Client) invoke(ctx context.Context, opt callOption, args interface{}, reply interface{}) error {
+func (client *Client) invoke(ctx context.Context, req *fasthttp.Request, args interface{}, reply interface{}, c callInfo, opts ...CallOption) error {
 	h := func(ctx context.Context, in interface{}) (interface{}, error) {
-		res, err := client.do(opt)
+		res, err := client.do(ctx, req)
+		if res != nil {
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				if tr, ok := tr.(*ClientTransport); ok {
+					for k, v := range res.Header {
+						tr.replyHeader[k] = v
+					}
+				}
+			}
+			cs := csAttempt{res: res}
+			for _, o := range opts {
+				o.after(&c, &cs)
+			}
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -248,42 +287,80 @@ func (client *Client) invoke(ctx context.Context, opt callOption, args interface
 		}
 		return reply, nil
 	}
+	if len(client.opts.middleware) > 0 {
+		h = middleware.Chain(client.opts.middleware...)(h)
+	}
 	_, err := h(ctx, args)
 	return err
 }
 
-func (client *Client) do(opt callOption) (*Response, error) {
-	var resp *Response
+// do sends the request, the response is returned together with
+// the decoded error once the server has replied.
+func (client *Client) do(ctx context.Context, req *fasthttp.Request) (*Response, error) {
 	var done func(context.Context, selector.DoneInfo)
 	if client.r != nil {
 		var (
-			err  error
-			node selector.Node
+			err     error
+			node    selector.Node
+			filters []selector.Filter
 		)
-		if node, done, err = client.opts.selector.Select(context.Background()); err != nil {
+		if tr, ok := transport.FromClientContext(ctx); ok {
+			if tr, ok := tr.(*ClientTransport); ok {
+				filters = tr.SelectFilters()
+			}
+		}
+		if node, done, err = client.opts.selector.Select(ctx, selector.WithFilter(filters...)); err != nil {
 			return nil, errors.ServiceUnavailable("NODE_NOT_FOUND", err.Error())
 		}
-		client.cc.Host(node.Address())
+		req.URI().SetHost(node.Address())
+		req.Header.SetHost(node.Address())
 	}
-	err := client.cc.Parse()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	var res *Response
+	err := client.send(ctx, req, resp)
 	if err == nil {
-		err = client.opts.errorDecoder(context.Background(), resp)
+		res = newResponse(resp)
+		err = client.opts.errorDecoder(ctx, res)
+	}
+	if done != nil {
+		di := selector.DoneInfo{Err: err}
+		if res != nil {
+			di.ReplyMeta = headerCarrier(res.Header)
+			di.BytesSent = true
+			di.BytesReceived = true
+		}
+		done(ctx, di)
 	}
+	return res, err
+}
 
-	if err != nil {
-		return nil, err
+// newResponse copies the response, which is released after the call.
+func newResponse(resp *fasthttp.Response) *Response {
+	header := make(http.Header, resp.Header.Len())
+	resp.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+	return &Response{
+		Code:        resp.StatusCode(),
+		Body:        append([]byte(nil), resp.Body()...),
+		ContentType: string(resp.Header.ContentType()),
+		Header:      header,
 	}
-	code, body, errs := client.cc.Bytes()
-	resp = &Response{
-		Code:        code,
-		Body:        body,
-		Errors:      errs,
-		ContentType: opt.contentType,
+}
+
+// send sends the request with the earlier of the client timeout and the context deadline.
+func (client *Client) send(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) error {
+	deadline, ok := ctx.Deadline()
+	if client.opts.timeout > 0 {
+		if d := time.Now().Add(client.opts.timeout); !ok || d.Before(deadline) {
+			deadline, ok = d, true
+		}
 	}
-	if done != nil {
-		done(context.Background(), selector.DoneInfo{Err: err})
+	if ok {
+		return client.cc.DoDeadline(req, resp, deadline)
 	}
-	return resp, nil
+	return client.cc.Do(req, resp)
 }
 
 // Close tears down the Transport and all underlying connections.
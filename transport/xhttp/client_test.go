@@ -0,0 +1,331 @@
+package xhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/registry"
+	"github.com/go-kratos/kratos/v2/selector"
+	"github.com/go-kratos/kratos/v2/selector/wrr"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type testData struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+func newTestServer(t testing.TB) (*Server, string) {
+	srv := NewServer(
+		Address("127.0.0.1:0"),
+		FiberConfig(fiber.Config{DisableStartupMessage: true}),
+		Router(func(r fiber.Router) {
+			r.Post("/echo/:name", func(c *fiber.Ctx) error {
+				var in testData
+				if err := c.BodyParser(&in); err != nil {
+					return err
+				}
+				c.Set("x-md-server", c.Context().LocalAddr().String())
+				return c.JSON(testData{Path: c.Path(), Name: in.Name})
+			})
+			r.Post("/xml", func(c *fiber.Ctx) error {
+				var in testData
+				if err := c.BodyParser(&in); err != nil {
+					return err
+				}
+				c.Set("x-content-type", c.Get(fiber.HeaderContentType))
+				c.Set("x-md-echo", c.Get("x-md-global-name"))
+				return c.JSON(in)
+			})
+			r.Get("/error", func(c *fiber.Ctx) error {
+				return c.Status(fiber.StatusNotFound).JSON(errors.NotFound("USER_NOT_FOUND", "not found"))
+			})
+		}),
+	)
+	go func() {
+		if err := srv.Start(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	t.Cleanup(func() {
+		_ = srv.Stop(context.Background())
+	})
+	return srv, srv.lis.Addr().String()
+}
+
+func TestWithTimeout(t *testing.T) {
+	ov := 1 * time.Second
+	o := WithTimeout(ov)
+	co := &clientOptions{}
+	o(co)
+	assert.Equal(t, co.timeout, ov)
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	ov := &tls.Config{}
+	o := WithTLSConfig(ov)
+	co := &clientOptions{}
+	o(co)
+	assert.Same(t, ov, co.tlsConf)
+}
+
+func TestClient_Invoke(t *testing.T) {
+	_, addr := newTestServer(t)
+	client, err := NewClient(context.Background(), WithEndpoint(addr))
+	assert.NoError(t, err)
+	defer client.Close()
+
+	var res testData
+	err = client.Invoke(context.Background(), fiber.MethodPost, "/echo/kratos", &testData{Name: "kratos"}, &res)
+	assert.NoError(t, err)
+	assert.Equal(t, "/echo/kratos", res.Path)
+	assert.Equal(t, "kratos", res.Name)
+
+	err = client.Invoke(context.Background(), fiber.MethodGet, "/error", nil, &res)
+	assert.True(t, errors.IsNotFound(err))
+	assert.Equal(t, "USER_NOT_FOUND", errors.Reason(err))
+
+	err = client.Invoke(context.Background(), "HELLO", "/echo/kratos", nil, &res)
+	assert.True(t, errors.IsBadRequest(err))
+}
+
+func TestClient_InvokeConcurrent(t *testing.T) {
+	_, addr := newTestServer(t)
+	client, err := NewClient(context.Background(), WithEndpoint(addr), WithTimeout(10*time.Second))
+	assert.NoError(t, err)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("kratos-%d", i)
+			var res testData
+			err := client.Invoke(context.Background(), fiber.MethodPost, "/echo/"+name, &testData{Name: name}, &res)
+			assert.NoError(t, err)
+			assert.Equal(t, "/echo/"+name, res.Path)
+			assert.Equal(t, name, res.Name)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkClient(b *testing.B) {
+	_, addr := newTestServer(b)
+	client, err := NewClient(context.Background(), WithEndpoint(addr))
+	assert.NoError(b, err)
+	defer client.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var res testData
+			err := client.Invoke(context.Background(), fiber.MethodPost, "/echo/kratos", &testData{Name: "kratos"}, &res)
+			assert.NoError(b, err)
+		}
+	})
+}
+
+// BenchmarkHTTPClient calls the same handler with the transport/http client,
+// to compare the throughput with BenchmarkClient.
+func BenchmarkHTTPClient(b *testing.B) {
+	_, addr := newTestServer(b)
+	client, err := khttp.NewClient(context.Background(), khttp.WithEndpoint(addr))
+	assert.NoError(b, err)
+	defer client.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var res testData
+			err := client.Invoke(context.Background(), fiber.MethodPost, "/echo/kratos", &testData{Name: "kratos"}, &res)
+			assert.NoError(b, err)
+		}
+	})
+}
+
+func TestWithPoolLimits(t *testing.T) {
+	client, err := NewClient(context.Background(), WithEndpoint("127.0.0.1:8000"))
+	assert.NoError(t, err)
+	assert.Equal(t, fasthttp.DefaultMaxConnsPerHost, client.cc.MaxConnsPerHost)
+	assert.Equal(t, 2*time.Second, client.cc.MaxConnWaitTimeout)
+
+	client, err = NewClient(context.Background(), WithEndpoint("127.0.0.1:8000"),
+		WithMaxConnsPerHost(16), WithMaxConnWaitTimeout(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 16, client.cc.MaxConnsPerHost)
+	assert.Equal(t, time.Second, client.cc.MaxConnWaitTimeout)
+}
+
+func TestWithMiddleware(t *testing.T) {
+	o := &clientOptions{}
+	v := []middleware.Middleware{
+		func(middleware.Handler) middleware.Handler { return nil },
+	}
+	WithMiddleware(v...)(o)
+	assert.Equal(t, v, o.middleware)
+}
+
+func TestClient_InvokeWithMiddleware(t *testing.T) {
+	_, addr := newTestServer(t)
+	var (
+		kind        transport.Kind
+		operation   string
+		replyHeader string
+	)
+	m := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromClientContext(ctx)
+			if !ok {
+				return nil, errors.InternalServer("TRANSPORT", "client transport not found")
+			}
+			kind = tr.Kind()
+			operation = tr.Operation()
+			tr.RequestHeader().Set("x-md-global-name", "kratos")
+			reply, err := handler(ctx, req)
+			replyHeader = tr.ReplyHeader().Get("x-md-echo")
+			return reply, err
+		}
+	}
+	client, err := NewClient(context.Background(), WithEndpoint(addr), WithMiddleware(m))
+	assert.NoError(t, err)
+	defer client.Close()
+
+	var (
+		res    testData
+		header http.Header
+	)
+	err = client.Invoke(context.Background(), fiber.MethodPost, "/xml", &testData{Name: "kratos"}, &res,
+		ContentType("application/xml"),
+		Operation("/helloworld.Greeter/SayHello"),
+		Header(&header),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "kratos", res.Name)
+	assert.Equal(t, transport.KindXHTTP, kind)
+	assert.Equal(t, "/helloworld.Greeter/SayHello", operation)
+	assert.Equal(t, "kratos", replyHeader)
+	assert.Equal(t, "application/xml", header.Get("x-content-type"))
+}
+
+type mockDiscovery struct {
+	instances []*registry.ServiceInstance
+}
+
+func (d *mockDiscovery) GetService(ctx context.Context, serviceName string) ([]*registry.ServiceInstance, error) {
+	return d.instances, nil
+}
+
+func (d *mockDiscovery) Watch(ctx context.Context, serviceName string) (registry.Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &mockWatcher{ctx: ctx, cancel: cancel, instances: d.instances}, nil
+}
+
+type mockWatcher struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	instances []*registry.ServiceInstance
+	once      sync.Once
+}
+
+func (w *mockWatcher) Next() (instances []*registry.ServiceInstance, err error) {
+	w.once.Do(func() { instances = w.instances })
+	if instances != nil {
+		return instances, nil
+	}
+	<-w.ctx.Done()
+	return nil, w.ctx.Err()
+}
+
+func (w *mockWatcher) Stop() error {
+	w.cancel()
+	return nil
+}
+
+type mockSelector struct {
+	selector.Selector
+	mu    sync.Mutex
+	infos []selector.DoneInfo
+}
+
+func (s *mockSelector) Select(ctx context.Context, opts ...selector.SelectOption) (selector.Node, selector.DoneFunc, error) {
+	n, done, err := s.Selector.Select(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return n, func(ctx context.Context, di selector.DoneInfo) {
+		s.mu.Lock()
+		s.infos = append(s.infos, di)
+		s.mu.Unlock()
+		done(ctx, di)
+	}, nil
+}
+
+func TestWithFilter(t *testing.T) {
+	o := &clientOptions{}
+	v := []selector.Filter{
+		func(context.Context, []selector.Node) []selector.Node { return nil },
+	}
+	WithFilter(v...)(o)
+	assert.Equal(t, len(v), len(o.filters))
+}
+
+func TestClient_InvokeWithDiscovery(t *testing.T) {
+	_, addr1 := newTestServer(t)
+	_, addr2 := newTestServer(t)
+	dis := &mockDiscovery{instances: []*registry.ServiceInstance{
+		{ID: "1", Name: "helloworld", Endpoints: []string{"http://" + addr1}},
+		{ID: "2", Name: "helloworld", Endpoints: []string{"http://" + addr2}},
+	}}
+	sel := &mockSelector{Selector: wrr.New()}
+	filter := func(_ context.Context, nodes []selector.Node) []selector.Node {
+		filtered := make([]selector.Node, 0, len(nodes))
+		for _, n := range nodes {
+			if n.Address() == addr2 {
+				filtered = append(filtered, n)
+			}
+		}
+		return filtered
+	}
+	client, err := NewClient(context.Background(),
+		WithEndpoint("discovery:///helloworld"),
+		WithDiscovery(dis),
+		WithSelector(sel),
+		WithFilter(filter),
+		WithBlock(),
+	)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		var res testData
+		err = client.Invoke(context.Background(), fiber.MethodPost, "/echo/kratos", &testData{Name: "kratos"}, &res)
+		assert.NoError(t, err)
+	}
+	err = client.Invoke(context.Background(), fiber.MethodGet, "/error", nil, nil)
+	assert.True(t, errors.IsNotFound(err))
+
+	sel.mu.Lock()
+	defer sel.mu.Unlock()
+	assert.Equal(t, 4, len(sel.infos))
+	for _, di := range sel.infos[:3] {
+		assert.NoError(t, di.Err)
+		assert.True(t, di.BytesSent)
+		assert.True(t, di.BytesReceived)
+		assert.Equal(t, addr2, di.ReplyMeta.Get("x-md-server"))
+	}
+	assert.True(t, errors.IsNotFound(sel.infos[3].Err))
+}
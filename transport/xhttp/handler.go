@@ -0,0 +1,49 @@
+package xhttp
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport/xhttp/apistate"
+	"github.com/go-kratos/kratos/v2/transport/xhttp/binding"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TypedHandler is a handler with typed request and reply.
+type TypedHandler[Req any, Resp any] func(ctx context.Context, req *Req) (*Resp, error)
+
+// Typed converts a typed handler into a fiber handler. The request body, query,
+// headers and path params are bound into Req, the server middleware chain is applied,
+// and the reply or the kratos error is sent by apistate.
+//
+//   r.Get("/users/:id", xhttp.Typed(srv, svc.GetUser))
+func Typed[Req any, Resp any](s *Server, h TypedHandler[Req, Resp]) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		in := new(Req)
+		if err := binding.Bind(c, in); err != nil {
+			return apistate.Error[any]().WithError(err).Send(c)
+		}
+		reply, err := s.serve(c, in, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return h(ctx, req.(*Req))
+		})
+		if err != nil {
+			return apistate.Error[any]().WithError(errors.FromError(err)).Send(c)
+		}
+		out, _ := reply.(*Resp)
+		return apistate.Success[*Resp]().WithData(out).Send(c)
+	}
+}
+
+// DefaultErrorHandler converts the error returned by a fiber handler into
+// a kratos error, and sends it with the status code of the error.
+func DefaultErrorHandler(c *fiber.Ctx, err error) error {
+	return apistate.Error[any]().WithError(fromError(err)).Send(c)
+}
+
+// fromError converts err into a kratos error, keeping the status code of fiber errors.
+func fromError(err error) *errors.Error {
+	if fe := new(fiber.Error); errors.As(err, &fe) {
+		return errors.New(fe.Code, errors.UnknownReason, fe.Message)
+	}
+	return errors.FromError(err)
+}
@@ -0,0 +1,129 @@
+package xhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware/validate"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type helloRequest struct {
+	Name  string `json:"name" query:"name" form:"name"`
+	Phone string `json:"phone" query:"phone" form:"phone"`
+	Age   int    `json:"age" query:"age" form:"age"`
+}
+
+func (r *helloRequest) Validate() error {
+	if r.Age < 0 {
+		return stderrors.New("age must not be negative")
+	}
+	return nil
+}
+
+type helloReply struct {
+	Message string `json:"message"`
+}
+
+type helloResp struct {
+	Code     int32       `json:"code"`
+	Metadata *helloReply `json:"metadata"`
+	Reason   string      `json:"reason"`
+	Message  string      `json:"message"`
+}
+
+func TestTyped(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"), KratosMiddleware(validate.Validator()))
+	defer srv.lis.Close()
+	srv.Route(func(r fiber.Router) {
+		r.Post("/hello/:name", Typed(srv, func(ctx context.Context, req *helloRequest) (*helloReply, error) {
+			if req.Name == "error" {
+				return nil, errors.NotFound("USER_NOT_FOUND", "user not found")
+			}
+			return &helloReply{Message: req.Name + ":" + req.Phone}, nil
+		}))
+	})
+
+	tests := []struct {
+		path string
+		body string
+		code int
+		msg  string
+	}{
+		{"/hello/kratos?phone=123", ``, http.StatusOK, "kratos:123"},
+		{"/hello/kratos", `{"name":"fiber","phone":"456"}`, http.StatusOK, "kratos:456"},
+		{"/hello/kratos", `{"age":-1}`, http.StatusBadRequest, ""},
+		{"/hello/error", ``, http.StatusNotFound, ""},
+		{"/hello/kratos", `{bad json}`, http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, test.path, bytes.NewBufferString(test.body))
+		if test.body != "" {
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		}
+		resp, err := srv.server.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, test.code, resp.StatusCode, test.path)
+		if test.code == http.StatusOK {
+			var res helloResp
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+			assert.Equal(t, test.msg, res.Metadata.Message)
+		} else {
+			e := new(errors.Error)
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(e))
+			assert.Equal(t, int32(test.code), e.Code)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestDefaultErrorHandler(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"))
+	defer srv.lis.Close()
+	srv.Route(func(r fiber.Router) {
+		r.Get("/fiber", func(c *fiber.Ctx) error {
+			return fiber.ErrForbidden
+		})
+		r.Get("/kratos", func(c *fiber.Ctx) error {
+			return errors.Unauthorized("TOKEN_MISSING", "token is missing")
+		})
+		r.Get("/grpc", func(c *fiber.Ctx) error {
+			return status.Error(codes.NotFound, "user not found")
+		})
+		r.Get("/error", func(c *fiber.Ctx) error {
+			return stderrors.New("internal error")
+		})
+	})
+
+	tests := []struct {
+		path   string
+		code   int
+		reason string
+	}{
+		{"/fiber", http.StatusForbidden, errors.UnknownReason},
+		{"/kratos", http.StatusUnauthorized, "TOKEN_MISSING"},
+		{"/grpc", http.StatusNotFound, errors.UnknownReason},
+		{"/error", http.StatusInternalServerError, errors.UnknownReason},
+		{"/not-found", http.StatusNotFound, errors.UnknownReason},
+	}
+	for _, test := range tests {
+		resp, err := srv.server.Test(httptest.NewRequest(http.MethodGet, test.path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, test.code, resp.StatusCode, test.path)
+		assert.Equal(t, "application/json", resp.Header.Get(fiber.HeaderContentType))
+		e := new(errors.Error)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(e))
+		assert.Equal(t, int32(test.code), e.Code)
+		assert.Equal(t, test.reason, e.Reason)
+		resp.Body.Close()
+	}
+}
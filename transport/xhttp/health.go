@@ -0,0 +1,25 @@
+package xhttp
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/go-kratos/kratos/v2/health"
+)
+
+// Health returns the health reported by the server, nil when the Health option is not given.
+func (s *Server) Health() *health.Health {
+	return s.health
+}
+
+// healthHandler sends the health result, with 503 status code when it is not serving.
+func (s *Server) healthHandler(check func(context.Context) *health.Result) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		res := check(c.UserContext())
+		if !res.Serving() {
+			c.Status(fiber.StatusServiceUnavailable)
+		}
+		return c.JSON(res)
+	}
+}
@@ -0,0 +1,71 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/health"
+)
+
+func TestServer_Health(t *testing.T) {
+	h := health.New()
+	srv := NewServer(Address("127.0.0.1:0"), Health(h))
+	defer srv.lis.Close()
+	assert.Equal(t, h, srv.Health())
+
+	serve := func(path string) (int, string) {
+		resp, err := srv.server.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+	code, _ := serve(health.LivePath)
+	assert.Equal(t, http.StatusOK, code)
+	code, body := serve(health.ReadyPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"status":"SERVING"}`, body)
+
+	h.SetReady(false)
+	code, body = serve(health.ReadyPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.JSONEq(t, `{"status":"NOT_SERVING"}`, body)
+
+	h.SetReady(true)
+	h.AddChecker("db", func(ctx context.Context) error { return errors.New("db is down") })
+	code, body = serve(health.ReadyPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.JSONEq(t, `{"status":"NOT_SERVING","checks":{"db":"db is down"}}`, body)
+}
+
+func TestServer_HealthDisabled(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"))
+	defer srv.lis.Close()
+	assert.Nil(t, srv.Health())
+	srv.Route(func(r fiber.Router) {
+		r.Get(health.LivePath, func(c *fiber.Ctx) error {
+			return c.SendString("user")
+		})
+	})
+	resp, err := srv.server.Test(httptest.NewRequest(http.MethodGet, health.LivePath, nil))
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "user", string(body))
+
+	resp, err = srv.server.Test(httptest.NewRequest(http.MethodGet, health.ReadyPath, nil))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -3,17 +3,35 @@ package xhttp
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
+	"github.com/fasthttp/websocket"
 	"github.com/go-kratos/kratos/v2/encoding/msgpack"
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/health"
 	"github.com/go-kratos/kratos/v2/internal/endpoint"
 	"github.com/go-kratos/kratos/v2/internal/host"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/go-kratos/kratos/v2/transport/xhttp/apistate"
 	"github.com/gofiber/fiber/v2"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/reuseport"
+	"net"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var (
+	_ transport.Server     = (*Server)(nil)
+	_ transport.Endpointer = (*Server)(nil)
+	_ transport.Readier    = (*Server)(nil)
+	_ transport.Tracker    = (*Server)(nil)
+	_ health.Reporter      = (*Server)(nil)
+)
+
 // SupportPackageIsVersion1 These constants should not be referenced from any other code.
 const (
 	SupportPackageIsVersion1 = true
@@ -37,7 +55,7 @@ func Address(addr string) ServerOption {
 	}
 }
 
-// Timeout with server timeout.
+// Timeout with server timeout, the requests have no deadline by default.
 func Timeout(timeout time.Duration) ServerOption {
 	return func(s *Server) {
 		s.timeout = timeout
@@ -58,6 +76,29 @@ func Middleware(ms ...fiber.Handler) ServerOption {
 	}
 }
 
+// KratosMiddleware with service middleware option, which is applied
+// to the routes wrapped by Server.Handle.
+func KratosMiddleware(m ...middleware.Middleware) ServerOption {
+	return func(s *Server) {
+		s.middleware = m
+	}
+}
+
+// TLSConfig with TLS config. Set ClientAuth and ClientCAs of the config
+// to verify client certificates (mTLS).
+func TLSConfig(c *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.tlsConf = c
+	}
+}
+
+// Listener with server lis
+func Listener(lis net.Listener) ServerOption {
+	return func(s *Server) {
+		s.lis = lis
+	}
+}
+
 // Router with server router
 func Router(r ...initRouters) ServerOption {
 	return func(s *Server) {
@@ -72,35 +113,86 @@ func FiberConfig(cfg fiber.Config) ServerOption {
 	}
 }
 
+// WebSocketUpgrader with the upgrader of the WebSocket connections,
+// for example to check the origin of cross-origin browser clients.
+func WebSocketUpgrader(u *websocket.FastHTTPUpgrader) ServerOption {
+	return func(s *Server) {
+		s.upgrader = u
+	}
+}
+
+// Health with the health reported by the /healthz and /readyz endpoints,
+// which are only served when it is given. It can be shared with the other
+// servers of the app.
+func Health(h *health.Health) ServerOption {
+	return func(s *Server) {
+		s.health = h
+	}
+}
+
 // initRouters is a function to initialize routers.
 type initRouters func(r fiber.Router)
 
+// Server is a FIBER server wrapper.
 type Server struct {
-	server   *fiber.App
-	baseCtx  context.Context
-	tlsConf  *tls.Config
-	endpoint *url.URL
-	err      error
-	network  string
-	address  string
-	config   fiber.Config
-	ms       []fiber.Handler
-	router   []initRouters
-	timeout  time.Duration
-	log      *log.Helper
+	server     *fiber.App
+	baseCtx    context.Context
+	lis        net.Listener
+	tlsConf    *tls.Config
+	endpoint   *url.URL
+	err        error
+	network    string
+	address    string
+	config     fiber.Config
+	ms         []fiber.Handler
+	middleware []middleware.Middleware
+	router     []initRouters
+	timeout    time.Duration
+	upgrader   *websocket.FastHTTPUpgrader
+	streams    streamSet
+	health     *health.Health
+	ready      chan struct{}
+	readyOnce  sync.Once
+	active     int32
+	log        *log.Helper
 }
 
 // NewServer creates an HTTP server by options.
 func NewServer(opts ...ServerOption) *Server {
 	srv := &Server{
-		network: "tcp",
-		address: ":0",
-		log:     log.NewHelper(log.DefaultLogger),
+		baseCtx:  context.Background(),
+		network:  "tcp",
+		address:  ":0",
+		upgrader: &websocket.FastHTTPUpgrader{},
+		ready:    make(chan struct{}),
+		log:      log.NewHelper(log.DefaultLogger),
 	}
 	for _, o := range opts {
 		o(srv)
 	}
+	if srv.config.ErrorHandler == nil {
+		srv.config.ErrorHandler = DefaultErrorHandler
+	}
+	if srv.config.Prefork {
+		// the prefork children listen on the network of the server,
+		// which is tcp4 or tcp6 only.
+		if srv.network == fiber.NetworkTCP {
+			srv.network = fiber.NetworkTCP4
+		}
+		srv.config.Network = srv.network
+	}
 	srv.server = fiber.New(srv.config)
+	// the hooks run once the listener is served, which is in the children
+	// processes only when preforking.
+	srv.server.Hooks().OnListen(func() error {
+		srv.readyOnce.Do(func() { close(srv.ready) })
+		return nil
+	})
+	srv.server.Use(srv.filter())
+	if srv.health != nil {
+		srv.server.Get(health.LivePath, srv.healthHandler(srv.health.Live))
+		srv.server.Get(health.ReadyPath, srv.healthHandler(srv.health.Ready))
+	}
 	for _, m := range srv.ms {
 		srv.server.Use(m)
 	}
@@ -113,15 +205,26 @@ func NewServer(opts ...ServerOption) *Server {
 
 // Serve serves the server by options.
 func (s *Server) Serve() error {
-	return s.server.Listen(s.address)
+	if s.config.Prefork {
+		// the prefork children bind their own listeners on the same address.
+		addr := s.lis.Addr().String()
+		_ = s.lis.Close()
+		return s.server.Listen(addr)
+	}
+	return s.server.Listener(s.lis)
 }
 
-// ServeTLS TODO: not implemented yet
+// ServeTLS serves the server with the TLS config.
 func (s *Server) ServeTLS() error {
-	return s.server.Listen(s.address)
+	if s.config.Prefork {
+		return fmt.Errorf("[fiber server] prefork is not supported with tls config")
+	}
+	return s.server.Listener(tls.NewListener(s.lis, s.tlsConf))
 }
 
-// Endpoint returns the endpoint of the server.
+// Endpoint return a real address to registry endpoint.
+// examples:
+//   http://127.0.0.1:8000?isSecure=false
 func (s *Server) Endpoint() (*url.URL, error) {
 	if s.err != nil {
 		return nil, s.err
@@ -135,7 +238,12 @@ func (s *Server) Start(ctx context.Context) error {
 		return s.err
 	}
 	s.baseCtx = ctx
-	s.log.Infof("[FIBER] server listening on: %s", s.address)
+	s.log.Infof("[FIBER] server listening on: %s", s.lis.Addr().String())
+	if s.config.Prefork && !fiber.IsChild() {
+		done := make(chan struct{})
+		defer close(done)
+		go s.waitPrefork(s.lis.Addr().String(), done)
+	}
 	var err error
 	if s.tlsConf != nil {
 		err = s.ServeTLS()
@@ -148,9 +256,45 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop stop the FIBER server.
+// waitPrefork marks the server ready once a prefork child accepts the connections
+// on addr, as the master process does not serve them itself.
+func (s *Server) waitPrefork(addr string, done <-chan struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		conn, err := net.DialTimeout(s.network, addr, time.Second)
+		if err != nil {
+			continue
+		}
+		_ = conn.Close()
+		s.readyOnce.Do(func() { close(s.ready) })
+		return
+	}
+}
+
+// Ready returns a channel closed once the FIBER server is serving.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// ActiveRequests returns the number of in-flight requests,
+// including the open SSE and WebSocket streams.
+func (s *Server) ActiveRequests() int {
+	return int(atomic.LoadInt32(&s.active)) + s.streams.count()
+}
+
+// Stop stop the FIBER server, the open SSE and WebSocket streams
+// are closed before the server is shut down.
 func (s *Server) Stop(ctx context.Context) error {
 	s.log.Info("[FIBER] server stopping")
+	if err := s.streams.close(ctx); err != nil {
+		s.log.Warnf("[FIBER] streams are not closed: %v", err)
+	}
 	return s.server.Shutdown()
 }
 
@@ -159,6 +303,19 @@ func (s *Server) Route(init initRouters) {
 	init(s.server)
 }
 
+// Handle wraps a fiber handler with the server middleware chain.
+// The route path is used as the transport operation and path template,
+// for example: /users/:id
+func (s *Server) Handle(h fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		_, err := s.serve(c, c, func(ctx context.Context, req interface{}) (interface{}, error) {
+			c.SetUserContext(ctx)
+			return nil, h(c)
+		})
+		return err
+	}
+}
+
 // OnStart registers a callback function that is invoked when the server is started.
 func (s *Server) OnStart(f func() error) {
 	s.server.Hooks().OnListen(f)
@@ -169,12 +326,97 @@ func (s *Server) OnStop(f func() error) {
 	s.server.Hooks().OnShutdown(f)
 }
 
+// filter injects the server transport into the user context of every request,
+// the context is canceled when the request exceeds the server timeout.
+func (s *Server) filter() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var (
+			ctx    context.Context
+			cancel context.CancelFunc
+		)
+		if s.timeout > 0 {
+			ctx, cancel = context.WithTimeout(s.baseCtx, s.timeout)
+		} else {
+			ctx, cancel = context.WithCancel(s.baseCtx)
+		}
+		defer cancel()
+		c.SetUserContext(transport.NewServerContext(ctx, s.newTransport(c)))
+		atomic.AddInt32(&s.active, 1)
+		defer atomic.AddInt32(&s.active, -1)
+		err := c.Next()
+		// only the handlers giving up on the deadline are answered with a timeout,
+		// the responses completed after it are kept.
+		if errors.Is(err, context.DeadlineExceeded) {
+			return apistate.Error[any]().WithError(errors.GatewayTimeout("GATEWAY_TIMEOUT", err.Error())).Send(c)
+		}
+		return err
+	}
+}
+
+// serve runs the handler with the server middleware chain in the request context.
+func (s *Server) serve(c *fiber.Ctx, req interface{}, h middleware.Handler) (interface{}, error) {
+	ctx := c.UserContext()
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		tr = s.newTransport(c)
+		ctx = transport.NewServerContext(ctx, tr)
+	}
+	if tr, ok := tr.(*Transport); ok {
+		tr.operation = c.Route().Path
+		tr.pathTemplate = c.Route().Path
+	}
+	if len(s.middleware) > 0 {
+		h = middleware.Chain(s.middleware...)(h)
+	}
+	return h(ctx, req)
+}
+
+func (s *Server) newTransport(c *fiber.Ctx) *Transport {
+	return &Transport{
+		endpoint:     s.endpoint.String(),
+		operation:    c.Path(),
+		reqHeader:    NewRequestHeader(c),
+		replyHeader:  NewReplyHeaderCarrier(c),
+		request:      c,
+		pathTemplate: c.Path(),
+	}
+}
+
 // listenAndEndpoint listen and get the endpoint.
 func (s *Server) listenAndEndpoint() error {
-	addr, err := host.ExtractEndpoint(s.address)
+	if s.config.Prefork {
+		// the children bind their own listeners on the address of the endpoint.
+		if s.lis != nil {
+			return fmt.Errorf("[fiber server] prefork is not supported with listener option")
+		}
+		if _, port, err := host.ExtractHostPort(s.address); err != nil || port == 0 {
+			return fmt.Errorf("[fiber server] prefork requires a fixed port address, got %q", s.address)
+		}
+	}
+	if s.lis == nil {
+		lis, err := s.listen()
+		if err != nil {
+			return err
+		}
+		s.lis = lis
+	}
+	if addr, ok := s.lis.Addr().(*net.UnixAddr); ok {
+		s.endpoint = &url.URL{Scheme: "unix", Path: addr.Name}
+		return nil
+	}
+	addr, err := host.Extract(s.address, s.lis)
 	if err != nil {
+		_ = s.lis.Close()
 		return err
 	}
 	s.endpoint = endpoint.NewEndpoint("http", addr, s.tlsConf != nil)
 	return nil
 }
+
+func (s *Server) listen() (net.Listener, error) {
+	if s.config.Prefork {
+		// the address is shared with the prefork children by SO_REUSEPORT.
+		return reuseport.Listen(s.network, s.address)
+	}
+	return net.Listen(s.network, s.address)
+}
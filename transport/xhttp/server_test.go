@@ -1,13 +1,26 @@
 package xhttp
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"github.com/go-kratos/kratos/v2"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/stretchr/testify/assert"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestServer(t *testing.T) {
@@ -43,3 +56,236 @@ func TestServer(t *testing.T) {
 		log.Fatal(err)
 	}
 }
+
+func TestServer_Handle(t *testing.T) {
+	var (
+		operation    string
+		pathTemplate string
+		kind         transport.Kind
+	)
+	m := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				kind = tr.Kind()
+				operation = tr.Operation()
+				if ht, ok := tr.(Transporter); ok {
+					pathTemplate = ht.PathTemplate()
+				}
+				tr.ReplyHeader().Set("x-kratos", "middleware")
+			}
+			return handler(ctx, req)
+		}
+	}
+	srv := NewServer(Address("127.0.0.1:0"), KratosMiddleware(m))
+	srv.Route(func(r fiber.Router) {
+		r.Get("/users/:name", srv.Handle(func(c *fiber.Ctx) error {
+			return c.SendString("hello " + c.Params("name"))
+		}))
+	})
+	defer srv.lis.Close()
+	resp, err := srv.server.Test(httptest.NewRequest(http.MethodGet, "/users/kratos", nil))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello kratos", string(body))
+	assert.Equal(t, "middleware", resp.Header.Get("x-kratos"))
+	assert.Equal(t, transport.KindXHTTP, kind)
+	assert.Equal(t, "/users/:name", operation)
+	assert.Equal(t, "/users/:name", pathTemplate)
+}
+
+func TestTLSConfig(t *testing.T) {
+	v := &tls.Config{}
+	o := &Server{}
+	TLSConfig(v)(o)
+	assert.Equal(t, v, o.tlsConf)
+
+	srv := NewServer(Address("127.0.0.1:0"), TLSConfig(v))
+	e, err := srv.Endpoint()
+	assert.NoError(t, err)
+	assert.Equal(t, "http", e.Scheme)
+	assert.Equal(t, "true", e.Query().Get("isSecure"))
+	assert.NoError(t, srv.lis.Close())
+}
+
+func TestListener(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	srv := NewServer(Address("127.0.0.1:0"), Listener(lis))
+	assert.Same(t, lis, srv.lis)
+	e, err := srv.Endpoint()
+	assert.NoError(t, err)
+	assert.Equal(t, lis.Addr().String(), e.Host)
+	assert.NoError(t, lis.Close())
+}
+
+func TestServer_Endpoint(t *testing.T) {
+	srv := NewServer()
+	e, err := srv.Endpoint()
+	assert.NoError(t, err)
+	if strings.HasSuffix(e.Host, ":0") {
+		t.Fatal(e)
+	}
+	assert.NoError(t, srv.lis.Close())
+}
+
+func TestServer_ServeTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := ts.TLS.Certificates[0]
+	client := ts.Client()
+	ts.Close()
+
+	srv := NewServer(
+		Address("127.0.0.1:0"),
+		TLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		Router(func(r fiber.Router) {
+			r.Get("/index", func(c *fiber.Ctx) error {
+				return c.SendString("secure")
+			})
+		}),
+	)
+	e, err := srv.Endpoint()
+	assert.NoError(t, err)
+	go func() {
+		if err := srv.Start(context.Background()); err != nil {
+			panic(err)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := client.Get("https://" + e.Host + "/index")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "secure", string(body))
+	assert.NotNil(t, resp.TLS)
+	assert.NoError(t, srv.Stop(context.Background()))
+}
+
+func TestServer_Timeout(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"), Timeout(50*time.Millisecond))
+	srv.Route(func(r fiber.Router) {
+		r.Get("/deadline", srv.Handle(func(c *fiber.Ctx) error {
+			if _, ok := c.UserContext().Deadline(); !ok {
+				return c.SendStatus(http.StatusInternalServerError)
+			}
+			return c.SendString("deadline")
+		}))
+		r.Get("/slow", srv.Handle(func(c *fiber.Ctx) error {
+			<-c.UserContext().Done()
+			return c.UserContext().Err()
+		}))
+		r.Get("/late", srv.Handle(func(c *fiber.Ctx) error {
+			time.Sleep(100 * time.Millisecond)
+			return c.Status(http.StatusCreated).SendString("created")
+		}))
+	})
+	defer srv.lis.Close()
+
+	resp, err := srv.server.Test(httptest.NewRequest(http.MethodGet, "/deadline", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = srv.server.Test(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	e := new(errors.Error)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(e))
+	assert.True(t, errors.IsGatewayTimeout(e))
+
+	// the response completed after the deadline is kept.
+	resp, err = srv.server.Test(httptest.NewRequest(http.MethodGet, "/late", nil))
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "created", string(body))
+
+	// no deadline is set by default.
+	srv = NewServer(Address("127.0.0.1:0"))
+	defer srv.lis.Close()
+	srv.Route(func(r fiber.Router) {
+		r.Get("/deadline", func(c *fiber.Ctx) error {
+			_, ok := c.UserContext().Deadline()
+			return c.SendString(strconv.FormatBool(ok))
+		})
+	})
+	resp, err = srv.server.Test(httptest.NewRequest(http.MethodGet, "/deadline", nil))
+	assert.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "false", string(body))
+}
+
+func TestServer_Ready(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"))
+	select {
+	case <-srv.Ready():
+		t.Fatal("server is ready before it is started")
+	default:
+	}
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	select {
+	case <-srv.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("server is not ready")
+	}
+	conn, err := net.Dial("tcp", srv.lis.Addr().String())
+	assert.NoError(t, err)
+	conn.Close()
+	assert.NoError(t, srv.Stop(context.Background()))
+}
+
+func TestServer_WaitPrefork(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"))
+	addr := srv.lis.Addr().String()
+	// the master does not serve the listener, the children bind their own.
+	assert.NoError(t, srv.lis.Close())
+	done := make(chan struct{})
+	defer close(done)
+	go srv.waitPrefork(addr, done)
+	select {
+	case <-srv.Ready():
+		t.Fatal("server is ready before a child listens")
+	case <-time.After(300 * time.Millisecond):
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	select {
+	case <-srv.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("server is not ready")
+	}
+}
+
+func TestServer_PreforkOptions(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	srv := NewServer(FiberConfig(fiber.Config{Prefork: true}), Listener(lis))
+	_, err = srv.Endpoint()
+	assert.Error(t, err)
+
+	srv = NewServer(FiberConfig(fiber.Config{Prefork: true}), Address("127.0.0.1:0"))
+	_, err = srv.Endpoint()
+	assert.Error(t, err)
+
+	srv = NewServer(FiberConfig(fiber.Config{Prefork: true}), Network("tcp6"), Address("[::1]:18001"))
+	assert.Equal(t, "tcp6", srv.server.Config().Network)
+	if srv.err == nil {
+		srv.lis.Close()
+	}
+}
@@ -0,0 +1,268 @@
+package xhttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// closeTimeout is the time given to the close handshake of a WebSocket connection.
+const closeTimeout = time.Second
+
+// ErrStreamClosed is returned when a stream is opened while the server is stopping.
+var ErrStreamClosed = errors.ServiceUnavailable("STREAM_CLOSED", "server is stopping")
+
+// SSEHandler serves a Server-Sent Events stream. The context keeps the values
+// of the request context, and is canceled when the server stops.
+type SSEHandler func(ctx context.Context, stream *EventStream) error
+
+// WebSocketHandler serves a WebSocket connection. The context keeps the values
+// of the request context, and is canceled when the server stops.
+type WebSocketHandler func(ctx context.Context, conn *websocket.Conn) error
+
+// Event is a Server-Sent Event.
+type Event struct {
+	ID    string
+	Event string
+	Data  []byte
+	Retry time.Duration
+}
+
+// EventStream writes the events of a Server-Sent Events stream.
+type EventStream struct {
+	mu  sync.Mutex
+	ctx context.Context
+	w   *bufio.Writer
+}
+
+// Context returns the stream context.
+func (s *EventStream) Context() context.Context {
+	return s.ctx
+}
+
+// Send writes the event and flushes it to the client,
+// an error is returned once the client is gone or the stream is closed.
+func (s *EventStream) Send(e *Event) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.ID != "" {
+		s.writeField("id", []byte(e.ID))
+	}
+	if e.Event != "" {
+		s.writeField("event", []byte(e.Event))
+	}
+	if e.Retry > 0 {
+		s.writeField("retry", []byte(strconv.FormatInt(e.Retry.Milliseconds(), 10)))
+	}
+	for _, line := range bytes.Split(e.Data, []byte("\n")) {
+		s.writeField("data", line)
+	}
+	_ = s.w.WriteByte('\n')
+	return s.w.Flush()
+}
+
+func (s *EventStream) writeField(name string, value []byte) {
+	_, _ = s.w.WriteString(name)
+	_, _ = s.w.WriteString(": ")
+	_, _ = s.w.Write(value)
+	_ = s.w.WriteByte('\n')
+}
+
+// SSE returns a fiber handler serving a Server-Sent Events stream. The server
+// middleware chain is applied before the stream is opened, for example:
+//
+//   r.Get("/events", srv.SSE(func(ctx context.Context, stream *xhttp.EventStream) error {
+//       return stream.Send(&xhttp.Event{Data: []byte("hello")})
+//   }))
+func (s *Server) SSE(h SSEHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		_, err := s.serve(c, c, func(ctx context.Context, req interface{}) (interface{}, error) {
+			ctx, done, err := s.streams.open(s.baseCtx, ctx)
+			if err != nil {
+				return nil, err
+			}
+			c.Set(fiber.HeaderContentType, "text/event-stream")
+			c.Set(fiber.HeaderCacheControl, "no-cache")
+			c.Set(fiber.HeaderConnection, "keep-alive")
+			c.Set("X-Accel-Buffering", "no")
+			// the stream is written once the handler returns.
+			c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+				defer done()
+				if err := h(ctx, &EventStream{ctx: ctx, w: w}); err != nil && ctx.Err() == nil {
+					s.log.Errorf("[FIBER] event stream error: %v", err)
+				}
+			})
+			return nil, nil
+		})
+		return err
+	}
+}
+
+// WebSocket returns a fiber handler serving a WebSocket connection. The server
+// middleware chain is applied before the connection is upgraded, for example:
+//
+//   r.Get("/ws", srv.WebSocket(func(ctx context.Context, conn *websocket.Conn) error {
+//       return conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+//   }))
+func (s *Server) WebSocket(h WebSocketHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !websocket.FastHTTPIsWebSocketUpgrade(c.Context()) {
+			return fiber.ErrUpgradeRequired
+		}
+		_, err := s.serve(c, c, func(ctx context.Context, req interface{}) (interface{}, error) {
+			ctx, done, err := s.streams.open(s.baseCtx, ctx)
+			if err != nil {
+				return nil, err
+			}
+			// the connection is served once the handler returns.
+			err = s.upgrader.Upgrade(c.Context(), func(conn *websocket.Conn) {
+				defer done()
+				s.serveWebSocket(ctx, conn, h)
+			})
+			if err != nil {
+				done()
+				return nil, errors.BadRequest("WEBSOCKET_UPGRADE", err.Error())
+			}
+			return nil, nil
+		})
+		return err
+	}
+}
+
+func (s *Server) serveWebSocket(ctx context.Context, conn *websocket.Conn, h WebSocketHandler) {
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			// the server is stopping, the handler is unblocked once the client
+			// answers the close message or the close timeout is reached.
+			deadline := time.Now().Add(closeTimeout)
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server stopping"), deadline)
+			_ = conn.SetReadDeadline(deadline)
+		case <-finished:
+		}
+	}()
+	err := h(ctx, conn)
+	close(finished)
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err != nil && ctx.Err() == nil {
+		s.log.Errorf("[FIBER] websocket error: %v", err)
+		msg = websocket.FormatCloseMessage(websocket.CloseInternalServerErr, errors.FromError(err).Message)
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeTimeout))
+	_ = conn.Close()
+}
+
+// streamContext keeps the values of the request context,
+// while its lifetime is bound to the stream instead of the request.
+type streamContext struct {
+	context.Context
+	values context.Context
+}
+
+// Value returns the value of the request context.
+func (c *streamContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// streamSet tracks the open streams, so that they are closed when the server stops.
+type streamSet struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closed  bool
+	seq     uint64
+	cancels map[uint64]context.CancelFunc
+}
+
+// open returns the stream context derived from base with the values of the
+// request context, and the func to call when the stream is finished.
+func (ss *streamSet) open(base context.Context, ctx context.Context) (context.Context, func(), error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.closed {
+		return nil, nil, ErrStreamClosed
+	}
+	if ss.cancels == nil {
+		ss.cancels = make(map[uint64]context.CancelFunc)
+	}
+	// the request is released once the handler returns,
+	// so the stream keeps a copy of its transport.
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		if tr, ok := tr.(*Transport); ok {
+			ctx = transport.NewServerContext(ctx, tr.detach())
+		}
+	}
+	sctx, cancel := context.WithCancel(base)
+	ss.seq++
+	id := ss.seq
+	ss.cancels[id] = cancel
+	ss.wg.Add(1)
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			cancel()
+			ss.mu.Lock()
+			delete(ss.cancels, id)
+			ss.mu.Unlock()
+			ss.wg.Done()
+		})
+	}
+	return &streamContext{Context: sctx, values: ctx}, done, nil
+}
+
+// count returns the number of open streams.
+func (ss *streamSet) count() int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return len(ss.cancels)
+}
+
+// close cancels the open streams and waits for them to finish,
+// no stream can be opened afterwards.
+func (ss *streamSet) close(ctx context.Context) error {
+	ss.mu.Lock()
+	ss.closed = true
+	for _, cancel := range ss.cancels {
+		cancel()
+	}
+	ss.mu.Unlock()
+	finished := make(chan struct{})
+	go func() {
+		ss.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// detach returns a copy of the transport which does not refer to the request.
+func (tr *Transport) detach() *Transport {
+	header := headerCarrier{}
+	for _, k := range tr.reqHeader.Keys() {
+		header.Set(k, tr.reqHeader.Get(k))
+	}
+	return &Transport{
+		endpoint:     tr.endpoint,
+		operation:    tr.operation,
+		reqHeader:    header,
+		replyHeader:  headerCarrier{},
+		pathTemplate: tr.pathTemplate,
+	}
+}
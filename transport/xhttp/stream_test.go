@@ -0,0 +1,100 @@
+package xhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+func TestServer_SSE(t *testing.T) {
+	var upgraded bool
+	m := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			upgraded = true
+			return handler(ctx, req)
+		}
+	}
+	srv := NewServer(Address("127.0.0.1:0"), KratosMiddleware(m))
+	srv.Route(func(r fiber.Router) {
+		r.Get("/events", srv.SSE(func(ctx context.Context, stream *EventStream) error {
+			tr, ok := transport.FromServerContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "kratos", tr.RequestHeader().Get("x-md-user"))
+			assert.Equal(t, "/events", tr.Operation())
+			if err := stream.Send(&Event{ID: "1", Event: "greeting", Data: []byte("hello\nkratos")}); err != nil {
+				return err
+			}
+			return stream.Send(&Event{Data: []byte("bye"), Retry: time.Second})
+		}))
+	})
+	defer srv.lis.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("x-md-user", "kratos")
+	resp, err := srv.server.Test(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.True(t, upgraded)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "id: 1\nevent: greeting\ndata: hello\ndata: kratos\n\nretry: 1000\ndata: bye\n\n", string(body))
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"))
+	srv.Route(func(r fiber.Router) {
+		r.Get("/ws", srv.WebSocket(func(ctx context.Context, conn *websocket.Conn) error {
+			for {
+				mt, msg, err := conn.ReadMessage()
+				if err != nil {
+					return err
+				}
+				if err = conn.WriteMessage(mt, msg); err != nil {
+					return err
+				}
+			}
+		}))
+	})
+	go func() {
+		_ = srv.Start(context.Background())
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := srv.server.Test(httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+srv.lis.Addr().String()+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+	_, msg, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(msg))
+	// the open stream is counted once the upgrade request has finished
+	assert.Eventually(t, func() bool { return srv.ActiveRequests() == 1 }, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, srv.Stop(ctx))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+	assert.Equal(t, 0, srv.ActiveRequests())
+
+	_, _, err = srv.streams.open(context.Background(), context.Background())
+	assert.Equal(t, ErrStreamClosed, err)
+}
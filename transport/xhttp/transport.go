@@ -2,11 +2,17 @@ package xhttp
 
 import (
 	"context"
+	"github.com/go-kratos/kratos/v2/selector"
 	"github.com/go-kratos/kratos/v2/transport"
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"net/http"
 )
 
-var _ Transporter = &Transport{}
+var (
+	_ Transporter           = &Transport{}
+	_ transport.Transporter = &ClientTransport{}
+)
 
 // Transporter is fasthttp Transporter
 type Transporter interface {
@@ -19,8 +25,8 @@ type Transporter interface {
 type Transport struct {
 	endpoint     string
 	operation    string
-	reqHeader    *reqHeaderCarrier
-	replyHeader  *replyHeaderCarrier
+	reqHeader    transport.Header
+	replyHeader  transport.Header
 	request      *fiber.Ctx
 	pathTemplate string
 }
@@ -40,7 +46,8 @@ func (tr *Transport) Operation() string {
 	return tr.operation
 }
 
-// Request returns the HTTP request.
+// Request returns the HTTP request,
+// which is nil once the request is upgraded to a stream.
 func (tr *Transport) Request() *fiber.Ctx {
 	return tr.request
 }
@@ -124,3 +131,99 @@ func (hc *replyHeaderCarrier) Keys() []string {
 	}
 	return keys
 }
+
+// ClientTransport is a FASTHTTP client transport.
+type ClientTransport struct {
+	endpoint     string
+	operation    string
+	reqHeader    clientHeaderCarrier
+	replyHeader  headerCarrier
+	request      *fasthttp.Request
+	pathTemplate string
+	filters      []selector.Filter
+}
+
+// Kind returns the transport kind.
+func (tr *ClientTransport) Kind() transport.Kind {
+	return transport.KindXHTTP
+}
+
+// Endpoint returns the transport endpoint.
+func (tr *ClientTransport) Endpoint() string {
+	return tr.endpoint
+}
+
+// Operation returns the transport operation.
+func (tr *ClientTransport) Operation() string {
+	return tr.operation
+}
+
+// Request returns the FASTHTTP request.
+func (tr *ClientTransport) Request() *fasthttp.Request {
+	return tr.request
+}
+
+// RequestHeader returns the request header.
+func (tr *ClientTransport) RequestHeader() transport.Header {
+	return tr.reqHeader
+}
+
+// ReplyHeader returns the reply header,
+// which is filled in after the response is received.
+func (tr *ClientTransport) ReplyHeader() transport.Header {
+	return tr.replyHeader
+}
+
+// PathTemplate returns the http path template.
+func (tr *ClientTransport) PathTemplate() string {
+	return tr.pathTemplate
+}
+
+// SelectFilters returns the client select filters.
+func (tr *ClientTransport) SelectFilters() []selector.Filter {
+	return tr.filters
+}
+
+type clientHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+// Get returns the value associated with the passed key.
+func (hc clientHeaderCarrier) Get(key string) string {
+	return string(hc.header.Peek(key))
+}
+
+// Set stores the key-value pair.
+func (hc clientHeaderCarrier) Set(key string, value string) {
+	hc.header.Set(key, value)
+}
+
+// Keys lists the keys stored in this carrier.
+func (hc clientHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, hc.header.Len())
+	hc.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+type headerCarrier http.Header
+
+// Get returns the value associated with the passed key.
+func (hc headerCarrier) Get(key string) string {
+	return http.Header(hc).Get(key)
+}
+
+// Set stores the key-value pair.
+func (hc headerCarrier) Set(key string, value string) {
+	http.Header(hc).Set(key, value)
+}
+
+// Keys lists the keys stored in this carrier.
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}